@@ -1,10 +1,10 @@
 package act
 
 import (
-	"errors"
-	"flag"
 	"fmt"
-	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //
@@ -14,12 +14,33 @@ type Term interface{}
 
 //
 // Pid incapsulates actor identificator and
-// channels to communicate to actor process
+// mailbox to communicate to actor process
 //
 type Pid struct {
 	id       uint64
-	inChan   chan interface{}
-	stopChan chan *stopReq
+	env      *Act   // environment the process is spawned in
+	node     *Node  // node of the remote process, nil for local
+	wireNode string // node of the decoded pid until it is resolved
+	mbox     *mailbox
+	prefix   string
+	name     interface{}
+	started  time.Time
+
+	processed    atomic.Uint64
+	status       atomic.Int32 // ProcessStatus
+	lastActivity atomic.Int64 // unix nano
+	timeoutAt    atomic.Int64 // unix nano, 0 if no inactivity timer
+
+	mu       sync.Mutex
+	exited   bool
+	trapExit bool
+	links    map[*Pid]struct{}
+	monitors map[MonitorRef]*Pid  // processes monitoring this one
+	watching map[MonitorRef]*Pid  // processes monitored by this one
+	names    map[nameKey]struct{} // names registered for this process
+	globals  map[string]struct{}  // global names of this process
+	nodes    map[*Node]struct{}   // nodes with links and monitors of this one
+	groups   map[groupKey]struct{}
 }
 
 //
@@ -29,97 +50,47 @@ type Opts struct {
 	Prefix   string
 	Name     interface{}
 	ChanSize uint32
-}
-
-type makePidResp struct {
-	pid    *Pid
-	oldPid bool
-	err    error
-}
-
-type makePidReq struct {
-	opts                  *Opts
-	returnPidIfRegistered bool
-	replyTo               chan<- makePidResp
-}
-
-type regNameReq struct {
-	prefix  string
-	name    interface{}
-	pid     *Pid
-	replyTo chan<- bool
-}
-
-type unregNameReq struct {
-	prefix string
-	name   interface{}
-}
-
-type whereNameReq struct {
-	prefix  string
-	name    interface{}
-	replyTo chan<- *Pid
+	Mailbox  MailboxPolicy // what to do when ChanSize messages are queued
+	TrapExit bool          // receive Exit messages instead of stopping with links
 }
 
 //
 // RegMap map of registered processes with same prefix
 //
 type RegMap map[interface{}]*Pid
-type wherePrefixReq struct {
-	prefix  string
-	replyTo chan<- RegMap
-}
-
-type registryChan struct {
-	makePidChan     chan makePidReq
-	regNameChan     chan regNameReq
-	unregNameChan   chan unregNameReq
-	whereNameChan   chan whereNameReq
-	wherePrefixChan chan wherePrefixReq
-}
 
+//
+// Act is the environment of processes with its own process registry
+//
 type Act struct {
-	serial     uint64
-	registry   *registryChan
-	registered map[string]RegMap
+	serial   uint64
+	registry *registry
+	groups   groups
+	procs    procTable
+	logger   atomic.Pointer[loggerBox]
+
+	crashHandler atomic.Pointer[crashHandlerBox]
+	hooks        atomic.Pointer[hooksBox]
+	codec        atomic.Pointer[codecBox]
+
+	nodes   nodeTable
+	globals globalTable
+	kinds   kindTable
 }
 
 // ---------------------------------------------------------------------------
-var nTrace bool
 var env *Act // default env
 
 func init() {
-	flag.BoolVar(&nTrace, "trace", false, "trace actors")
-
 	env = NewEnv()
 }
 
+//
+// NewEnv creates a new environment
+//
 func NewEnv() *Act {
-
-	registry := &registryChan{
-		makePidChan:     make(chan makePidReq),
-		regNameChan:     make(chan regNameReq),
-		unregNameChan:   make(chan unregNameReq),
-		whereNameChan:   make(chan whereNameReq),
-		wherePrefixChan: make(chan wherePrefixReq),
-	}
-
-	a := &Act{
-		registry:   registry,
-		registered: make(map[string]RegMap),
-	}
-
-	// without prefix
-	a.registered[""] = make(RegMap)
-
-	a.run()
-
-	return a
-}
-
-func nLog(f string, a ...interface{}) {
-	if nTrace {
-		log.Printf(f, a...)
+	return &Act{
+		registry: newRegistry(),
 	}
 }
 
@@ -224,6 +195,10 @@ func (a *Act) spawnOpts(
 		opts.ChanSize = 100
 	}
 
+	if a.procs.isClosed() {
+		return nil, false, ErrEnvShutdown
+	}
+
 	pid, oldPid, err := a.makePid(opts, returnPidIfRegistered)
 	if err != nil {
 		return nil, !oldPid, err
@@ -237,57 +212,73 @@ func (a *Act) spawnOpts(
 
 	initChan := make(chan Term)
 
+	if !a.procs.add(pid) {
+		pid.unregisterNames()
+		return nil, false, ErrEnvShutdown
+	}
+
 	go a.GenServerLoop(gs, opts.Prefix, opts.Name, initChan, pid, args...)
 	result := <-initChan
 
 	switch result := result.(type) {
 	case gsInitOk:
-	case *GsInitStop:
-		return nil, newPid, errors.New(result.Reason)
 	case error:
+		a.procs.remove(pid)
+		pid.logDebug("init failed", "reason", result)
 		return nil, newPid, result
 	}
 
+	pid.logDebug("spawn")
+
+	if hooks := pid.hooks(); hooks != nil {
+		pid.callHook("OnSpawn", func() { hooks.OnSpawn(pid) })
+	}
+
 	return pid, newPid, nil
 }
 
 //
-// Register associates the name with pid
+// Register associates the name with pid. The name is unregistered
+// when the process exits. GsNoProcError is returned if the process exited
 //
 func Register(name interface{}, pid *Pid) error {
 	return env.Register(name, pid)
 }
 
 func (a *Act) Register(name interface{}, pid *Pid) error {
-	replyChan := make(chan bool, 1)
-	r := regNameReq{name: name, pid: pid, replyTo: replyChan}
-	a.registry.regNameChan <- r
-	reply := <-replyChan
-
-	if reply == true {
+	old, ok := a.registry.register("", name, pid)
+	if ok {
+		pid.watchRemote()
 		return nil
 	}
 
+	if old == nil {
+		return GsNoProcError
+	}
+
 	return fmt.Errorf("name '%v' already registered", name)
 }
 
 //
-// RegisterPrefix associates the prefix + name with pid
+// RegisterPrefix associates the prefix + name with pid. The name is
+// unregistered when the process exits. GsNoProcError is returned if
+// the process exited
 //
 func RegisterPrefix(prefix string, name interface{}, pid *Pid) error {
 	return env.RegisterPrefix(prefix, name, pid)
 }
 
 func (a *Act) RegisterPrefix(prefix string, name interface{}, pid *Pid) error {
-	replyChan := make(chan bool, 1)
-	r := regNameReq{prefix: prefix, name: name, pid: pid, replyTo: replyChan}
-	a.registry.regNameChan <- r
-	reply := <-replyChan
-
-	if reply == true {
+	old, ok := a.registry.register(prefix, name, pid)
+	if ok {
+		pid.watchRemote()
 		return nil
 	}
 
+	if old == nil {
+		return GsNoProcError
+	}
+
 	return fmt.Errorf("name '%s/%v' already registered", prefix, name)
 }
 
@@ -299,8 +290,7 @@ func Unregister(name interface{}) {
 }
 
 func (a *Act) Unregister(name interface{}) {
-	r := unregNameReq{name: name}
-	a.registry.unregNameChan <- r
+	a.registry.unregister("", name)
 }
 
 //
@@ -315,8 +305,7 @@ func (a *Act) UnregisterPrefix(prefix string, name interface{}) {
 		return
 	}
 
-	r := unregNameReq{prefix: prefix, name: name}
-	a.registry.unregNameChan <- r
+	a.registry.unregister(prefix, name)
 }
 
 //
@@ -340,12 +329,7 @@ func WhereisPrefix(prefix string, name interface{}) *Pid {
 }
 
 func (a *Act) WhereisPrefix(prefix string, name interface{}) *Pid {
-	replyChan := make(chan *Pid, 1)
-	r := whereNameReq{prefix: prefix, name: name, replyTo: replyChan}
-	a.registry.whereNameChan <- r
-	pid := <-replyChan
-
-	return pid
+	return a.registry.whereis(prefix, name)
 }
 
 //
@@ -356,123 +340,42 @@ func Whereare(prefix string) RegMap {
 }
 
 func (a *Act) Whereare(prefix string) RegMap {
-	replyChan := make(chan RegMap, 1)
-	r := wherePrefixReq{prefix: prefix, replyTo: replyChan}
-	a.registry.wherePrefixChan <- r
-	regs := <-replyChan
-
-	return regs
+	return a.registry.whereare(prefix)
 }
 
 // ---------------------------------------------------------------------------
-func (a *Act) run() {
-	go a.registrator()
-}
-
-func (a *Act) registrator() {
-	for {
-
-		//
-		// first handle new registation
-		//
-		select {
-		case req := <-a.registry.makePidChan:
-			a.regNewPid(&req)
-			continue
-		default:
-		}
-
-		select {
-		case req := <-a.registry.makePidChan:
-			a.regNewPid(&req)
-		case req := <-a.registry.regNameChan:
-			if _, ok := a.registered[req.prefix]; !ok {
-				a.registered[req.prefix] = make(RegMap)
-			}
-			if _, ok := a.registered[req.prefix][req.name]; ok {
-				req.replyTo <- false // name registered
-			} else {
-				a.registered[req.prefix][req.name] = req.pid
-				req.replyTo <- true
-			}
-
-		case req := <-a.registry.unregNameChan:
-			if _, ok := a.registered[req.prefix]; ok {
-				delete(a.registered[req.prefix], req.name)
-			}
-
-		case req := <-a.registry.whereNameChan:
-			req.replyTo <- a.registered[req.prefix][req.name]
-
-		case req := <-a.registry.wherePrefixChan:
-			var rpids RegMap
-			if pids, ok := a.registered[req.prefix]; ok {
-				rpids = make(RegMap)
-				for k, v := range pids {
-					rpids[k] = v
-				}
-			}
-			req.replyTo <- rpids
-		}
+func (a *Act) makePid(
+	opts *Opts,
+	returnPidIfRegistered bool) (*Pid, bool, error) {
+
+	pid := &Pid{
+		id:       atomic.AddUint64(&a.serial, 1),
+		env:      a,
+		mbox:     newMailbox(int(opts.ChanSize), opts.Mailbox),
+		prefix:   opts.Prefix,
+		name:     opts.Name,
+		started:  time.Now(),
+		trapExit: opts.TrapExit,
 	}
-}
-
-func (a *Act) regNewPid(req *makePidReq) {
-
-	newPidCreated := true
-
-	var resp makePidResp
-	resp.pid = &Pid{id: a.serial + 1}
-	resp.oldPid = false
 
 	//
 	// register name with prefix if not empty
 	//
-	if req.opts.Name != nil {
-		if _, ok := a.registered[req.opts.Prefix]; ok {
-			// map with prefix exists
-			if pid, ok := a.registered[req.opts.Prefix][req.opts.Name]; ok {
-
-				newPidCreated = false
-
-				if req.returnPidIfRegistered == true {
-					resp.pid = pid
-					resp.oldPid = true
-				} else {
-					// name already registered
-					resp.pid = nil
-					resp.err =
-						fmt.Errorf("name '%s/%v' already registered for pid #%d",
-							req.opts.Prefix, req.opts.Name, pid.Id())
-				}
-			} else {
-				a.registered[req.opts.Prefix][req.opts.Name] = resp.pid
+	if opts.Name != nil {
+		old, ok := a.registry.register(opts.Prefix, opts.Name, pid)
+		if !ok {
+			if returnPidIfRegistered {
+				return old, true, nil
 			}
 
-		} else { // no maps with prefix
-			a.registered[req.opts.Prefix] = make(RegMap)
-			a.registered[req.opts.Prefix][req.opts.Name] = resp.pid
+			// name already registered
+			return nil, false,
+				fmt.Errorf("name '%s/%v' already registered for pid #%d",
+					opts.Prefix, opts.Name, old.Id())
 		}
 	}
 
-	if newPidCreated {
-		resp.pid.inChan = make(chan interface{}, req.opts.ChanSize)
-		resp.pid.stopChan = make(chan *stopReq)
-		a.serial++
-	}
-
-	req.replyTo <- resp
-}
-
-func (a *Act) makePid(opts *Opts, returnPidIfRegistered bool) (*Pid, bool, error) {
-	replyChan := make(chan makePidResp, 1)
-	a.registry.makePidChan <- makePidReq{
-		opts: opts,
-		returnPidIfRegistered: returnPidIfRegistered,
-		replyTo:               replyChan}
-	resp := <-replyChan
-
-	return resp.pid, resp.oldPid, resp.err
+	return pid, false, nil
 }
 
 //
@@ -2,6 +2,7 @@ package act
 
 import (
 	"fmt"
+	"sync/atomic"
 	"testing"
 )
 
@@ -74,3 +75,65 @@ func BenchmarkCastPid(b *testing.B) {
 		})
 	}
 }
+
+// ----------------------------------------------------------------------------
+// Registry benchmarks
+// ----------------------------------------------------------------------------
+var benchSerial uint64
+
+func BenchmarkRegisterParallel(b *testing.B) {
+	env := NewEnv()
+	pid, err := env.startServerOpts(&Opts{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer pid.Stop()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			name := atomic.AddUint64(&benchSerial, 1)
+			env.RegisterPrefix("bench", name, pid)
+			env.UnregisterPrefix("bench", name)
+		}
+	})
+}
+
+func BenchmarkWhereisParallel(b *testing.B) {
+	env := NewEnv()
+	for i := 0; i < 1000; i++ {
+		if _, err := env.startServerOpts(&Opts{Prefix: "bench", Name: i}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if env.WhereisPrefix("bench", i%1000) == nil {
+				b.Fatal("pid == nil")
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkSpawnRegisteredParallel(b *testing.B) {
+	env := NewEnv()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			name := atomic.AddUint64(&benchSerial, 1)
+			pid, err := env.startServerOpts(&Opts{Prefix: "session", Name: name})
+			if err != nil {
+				b.Fatal(err)
+			}
+			pid.Stop()
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package act
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+//
+// ClusterOpts configures the cluster membership of the environment
+//
+type ClusterOpts struct {
+	Seeds     []string      // addresses to connect to and reconnect after failures
+	Heartbeat time.Duration // interval of heartbeats, 1 second by default
+	Timeout   time.Duration // heartbeat timeout, 3 heartbeats by default
+}
+
+//
+// NodeUp is sent to processes monitoring the node when the node is connected
+//
+type NodeUp struct {
+	Node string
+}
+
+//
+// NodeDown is sent to processes monitoring the node when the connection to
+// the node is lost. Reason matches ErrNoConnection
+//
+type NodeDown struct {
+	Node   string
+	Reason error
+}
+
+//
+// ErrClusterStarted is returned by JoinCluster if the environment is
+// already in the cluster
+//
+var ErrClusterStarted = errors.New("cluster already started")
+
+//
+// cluster keeps connections to seed nodes and checks connected nodes
+// with heartbeats
+//
+type cluster struct {
+	env    *Act
+	opts   ClusterOpts
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	connecting map[string]bool
+	peers      map[string]*Node // nodes by dialled seed address
+	self       map[string]bool  // seeds with the address of the environment
+}
+
+// ---------------------------------------------------------------------------
+//
+// JoinCluster starts the cluster membership of the default environment
+//
+func JoinCluster(opts *ClusterOpts) error {
+	return env.JoinCluster(opts)
+}
+
+//
+// JoinCluster connects the environment to the seed nodes and starts
+// heartbeats of connected nodes. Seeds are reconnected after failures,
+// nodes which do not answer heartbeats in time are disconnected.
+// The membership is stopped by Shutdown
+//
+func (a *Act) JoinCluster(opts *ClusterOpts) error {
+	c := &cluster{
+		env:        a,
+		connecting: make(map[string]bool),
+		peers:      make(map[string]*Node),
+		self:       make(map[string]bool),
+	}
+	if opts != nil {
+		c.opts = *opts
+		c.opts.Seeds = append([]string(nil), opts.Seeds...)
+	}
+	if c.opts.Heartbeat <= 0 {
+		c.opts.Heartbeat = time.Second
+	}
+	if c.opts.Timeout <= 0 {
+		c.opts.Timeout = 3 * c.opts.Heartbeat
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	a.nodes.mu.Lock()
+	defer a.nodes.mu.Unlock()
+
+	if a.nodes.closed {
+		c.cancel()
+		return ErrEnvShutdown
+	}
+	if a.nodes.cluster != nil {
+		c.cancel()
+		return ErrClusterStarted
+	}
+	a.nodes.cluster = c
+
+	go c.run()
+
+	return nil
+}
+
+//
+// MonitorNode sends NodeUp and NodeDown to the process when the node is
+// connected and disconnected. NodeUp is sent at once if the node is
+// connected. Only local processes can monitor nodes
+//
+func (pid *Pid) MonitorNode(name string) {
+	if pid == nil || pid.node != nil {
+		return
+	}
+
+	a := pid.env
+
+	a.nodes.mu.Lock()
+	if a.nodes.monitors == nil {
+		a.nodes.monitors = make(map[string]map[*Pid]struct{})
+	}
+	if a.nodes.monitors[name] == nil {
+		a.nodes.monitors[name] = make(map[*Pid]struct{})
+	}
+	a.nodes.monitors[name][pid] = struct{}{}
+	_, up := a.nodes.byName[name]
+	a.nodes.mu.Unlock()
+
+	if up {
+		pid.castSystem(NodeUp{name})
+	}
+}
+
+//
+// DemonitorNode stops monitoring started by MonitorNode
+//
+func (pid *Pid) DemonitorNode(name string) {
+	if pid == nil || pid.node != nil {
+		return
+	}
+
+	a := pid.env
+
+	a.nodes.mu.Lock()
+	delete(a.nodes.monitors[name], pid)
+	if len(a.nodes.monitors[name]) == 0 {
+		delete(a.nodes.monitors, name)
+	}
+	a.nodes.mu.Unlock()
+}
+
+// ---------------------------------------------------------------------------
+func (c *cluster) run() {
+	ticker := time.NewTicker(c.opts.Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		c.connectSeeds()
+
+		for _, name := range c.env.Nodes() {
+			if n := c.env.Node(name); n != nil {
+				n.heartbeat(c.opts.Timeout)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+//
+// connectSeeds connects to seeds not connected, one attempt for a seed
+// at a time. Seeds are matched with nodes by the dialled address, the node
+// name may differ from it
+//
+func (c *cluster) connectSeeds() {
+	self := c.env.NodeName()
+
+	for _, seed := range c.opts.Seeds {
+		if seed == self || c.env.Node(seed) != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		peer := c.peers[seed]
+		busy := c.connecting[seed] || c.self[seed] ||
+			(peer != nil && peer.IsAlive())
+		if !busy {
+			c.connecting[seed] = true
+		}
+		c.mu.Unlock()
+
+		if busy {
+			continue
+		}
+
+		go func(seed string) {
+			defer func() {
+				c.mu.Lock()
+				delete(c.connecting, seed)
+				c.mu.Unlock()
+			}()
+
+			n, err := c.env.Connect(seed)
+
+			c.mu.Lock()
+			switch {
+			case err == nil:
+				c.peers[seed] = n
+			case errors.Is(err, errConnectSelf):
+				c.self[seed] = true
+			}
+			c.mu.Unlock()
+
+			if err != nil {
+				c.env.log().Debug("connect seed failed",
+					"node", seed, "error", err)
+			}
+		}(seed)
+	}
+}
+
+//
+// heartbeat checks the node answers in time, the node is disconnected if
+// it does not. A heartbeat is skipped while the previous one is in progress
+//
+func (n *Node) heartbeat(timeout time.Duration) {
+	if !n.beating.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer n.beating.Store(false)
+
+		ctx, cancel := context.WithTimeout(n.ctx, timeout)
+		defer cancel()
+
+		_, err := n.request(ctx, &frame{Type: frameHeartbeat})
+		if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+			return
+		}
+
+		n.log().Warn("node heartbeat timeout", "node", n.name)
+		n.closeReason(fmt.Errorf("%w: heartbeat timeout", ErrNoConnection))
+	}()
+}
+
+//
+// notify sends the message to processes monitoring the node, exited
+// processes are removed
+//
+func (t *nodeTable) notify(name string, msg Term) {
+	t.mu.Lock()
+	pids := make([]*Pid, 0, len(t.monitors[name]))
+	for pid := range t.monitors[name] {
+		pids = append(pids, pid)
+	}
+	t.mu.Unlock()
+
+	for _, pid := range pids {
+		if err := pid.castSystem(msg); err != nil {
+			pid.DemonitorNode(name)
+		}
+	}
+}
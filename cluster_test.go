@@ -0,0 +1,304 @@
+package act
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, events chan Term) Term {
+	t.Helper()
+
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("event must be sent")
+	}
+
+	return nil
+}
+
+func TestClusterSeeds(t *testing.T) {
+	a := NewEnv()
+	b := NewEnv()
+	defer b.Shutdown(contextTimeout(t))
+
+	if err := a.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	name := a.NodeName()
+
+	events := make(chan Term, 10)
+	w, err := b.Spawn(&gsWatch{events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.MonitorNode(name)
+
+	err = b.JoinCluster(&ClusterOpts{
+		Seeds:     []string{name},
+		Heartbeat: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := nextEvent(t, events); e != (NodeUp{name}) {
+		t.Fatalf("want NodeUp, got %#v", e)
+	}
+
+	a.Shutdown(contextTimeout(t))
+
+	e := nextEvent(t, events)
+	if down, ok := e.(NodeDown); !ok || down.Node != name ||
+		!errors.Is(down.Reason, ErrNoConnection) {
+		t.Fatalf("want NodeDown, got %#v", e)
+	}
+
+	// seed is reconnected when it is back
+	c := NewEnv()
+	defer c.Shutdown(contextTimeout(t))
+
+	if err := c.Listen(name); err != nil {
+		t.Skip("address is not reusable:", err)
+	}
+
+	if e := nextEvent(t, events); e != (NodeUp{name}) {
+		t.Fatalf("want NodeUp, got %#v", e)
+	}
+
+	if err := b.JoinCluster(nil); err != ErrClusterStarted {
+		t.Errorf("want ErrClusterStarted, got %v", err)
+	}
+}
+
+//
+// fakeNode accepts connection and answers hello only, frames are read if
+// read is true
+//
+func fakeNode(t *testing.T, read bool) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		l.Close()
+	})
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := readFrame(conn, GobCodec); err != nil {
+			return
+		}
+		hello := &frame{Type: frameHello, Node: l.Addr().String()}
+		if err := writeFrame(conn, GobCodec, hello); err != nil {
+			return
+		}
+
+		for read {
+			if _, err := readFrame(conn, GobCodec); err != nil {
+				return
+			}
+		}
+		<-done
+	}()
+
+	return l.Addr().String()
+}
+
+func TestClusterHeartbeatTimeout(t *testing.T) {
+	b := NewEnv()
+	defer b.Shutdown(contextTimeout(t))
+	b.SetLogger(new(testLogger))
+
+	name := fakeNode(t, true)
+
+	events := make(chan Term, 10)
+	w, err := b.Spawn(&gsWatch{events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.MonitorNode(name)
+
+	node, err := b.Connect(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := nextEvent(t, events); e != (NodeUp{name}) {
+		t.Fatalf("want NodeUp, got %#v", e)
+	}
+
+	pid := node.pid(1)
+	ref := w.Monitor(pid)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pid.Call("hello")
+		done <- err
+	}()
+
+	err = b.JoinCluster(&ClusterOpts{
+		Heartbeat: 20 * time.Millisecond,
+		Timeout:   50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if !IsNoProcError(err) {
+			t.Errorf("want GsNoProcError, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("call to dead node must fail")
+	}
+
+	var gotDown, gotNodeDown bool
+	for !gotDown || !gotNodeDown {
+		switch e := nextEvent(t, events).(type) {
+		case Down:
+			gotDown = e.Ref == ref && errors.Is(e.Reason, ErrNoConnection)
+		case NodeDown:
+			gotNodeDown = e.Node == name && errors.Is(e.Reason, ErrNoConnection)
+		}
+	}
+
+	if node.IsAlive() {
+		t.Error("dead node must be disconnected")
+	}
+	if _, err := pid.Call("hello"); !IsNoProcError(err) {
+		t.Errorf("want GsNoProcError, got %v", err)
+	}
+
+	w.DemonitorNode(name)
+}
+
+func TestClusterSeedAddress(t *testing.T) {
+	a := NewEnv()
+	b := NewEnv()
+	defer func() {
+		b.Shutdown(contextTimeout(t))
+		a.Shutdown(contextTimeout(t))
+	}()
+
+	if err := a.SetNodeName("node-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetNodeName("other"); err == nil {
+		t.Error("node name must not be changed")
+	}
+
+	// wildcard listener: named node-a, dialled by loopback address
+	if err := a.Listen(":0"); err != nil {
+		t.Fatal(err)
+	}
+	seed := loopbackAddr(a)
+
+	if err := b.Listen(":0"); err != nil {
+		t.Fatal(err)
+	}
+	selfSeed := loopbackAddr(b)
+
+	events := make(chan Term, 10)
+	w, err := b.Spawn(&gsWatch{events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.MonitorNode("node-a")
+
+	err = b.JoinCluster(&ClusterOpts{
+		Seeds:     []string{seed, selfSeed},
+		Heartbeat: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := nextEvent(t, events); e != (NodeUp{"node-a"}) {
+		t.Fatalf("want NodeUp, got %#v", e)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// the seed is not dialled again while connected
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %#v", e)
+	default:
+	}
+
+	c := b.nodes.cluster
+	c.mu.Lock()
+	peer := c.peers[seed]
+	self := c.self[selfSeed]
+	c.mu.Unlock()
+
+	if peer == nil || peer.Name() != "node-a" || !peer.IsAlive() {
+		t.Errorf("want seed %s connected to node-a", seed)
+	}
+	if !self {
+		t.Errorf("want seed %s detected as the node itself", selfSeed)
+	}
+}
+
+func loopbackAddr(a *Act) string {
+	a.nodes.mu.Lock()
+	defer a.nodes.mu.Unlock()
+
+	port := a.nodes.listeners[0].Addr().(*net.TCPAddr).Port
+
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
+
+func TestNodeNameWildcard(t *testing.T) {
+	a := NewEnv()
+	defer a.Shutdown(contextTimeout(t))
+
+	if err := a.Listen(":0"); err != nil {
+		t.Fatal(err)
+	}
+
+	host, _, err := net.SplitHostPort(a.NodeName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		t.Errorf("want dialable node name, got %s", a.NodeName())
+	}
+}
+
+func TestClusterWriteTimeout(t *testing.T) {
+	b := NewEnv()
+	defer b.Shutdown(contextTimeout(t))
+	b.SetLogger(new(testLogger))
+
+	node, err := b.Connect(fakeNode(t, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = b.JoinCluster(&ClusterOpts{
+		Heartbeat: 50 * time.Millisecond,
+		Timeout:   200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cast without deadline fills socket buffers
+	go node.pid(1).Cast(strings.Repeat("x", 32<<20))
+
+	waitDisconnect(t, node, 2*time.Second)
+}
@@ -0,0 +1,647 @@
+package act
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//
+// Codec marshals terms to bytes and back. Types of terms must be registered
+// with RegisterType, basic types, pids and Gs* types are registered
+//
+type Codec interface {
+	Name() string
+	Marshal(t Term) ([]byte, error)
+	Unmarshal(b []byte) (Term, error)
+}
+
+var (
+	// GobCodec encodes terms with encoding/gob
+	GobCodec Codec = gobCodec{}
+	// JSONCodec encodes terms with encoding/json as objects with type name
+	// and value
+	JSONCodec Codec = jsonCodec{}
+)
+
+//
+// typeRegistry maps names to types of terms
+//
+type typeRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]reflect.Type
+	names  map[reflect.Type]string
+	codecs map[string]Codec
+}
+
+var types = typeRegistry{
+	byName: make(map[string]reflect.Type),
+	names:  make(map[reflect.Type]string),
+	codecs: map[string]Codec{
+		GobCodec.Name():  GobCodec,
+		JSONCodec.Name(): JSONCodec,
+	},
+}
+
+func init() {
+	for name, prototype := range map[string]interface{}{
+		"string":  "",
+		"bool":    false,
+		"int":     0,
+		"int8":    int8(0),
+		"int16":   int16(0),
+		"int32":   int32(0),
+		"int64":   int64(0),
+		"uint":    uint(0),
+		"uint8":   uint8(0),
+		"uint16":  uint16(0),
+		"uint32":  uint32(0),
+		"uint64":  uint64(0),
+		"float32": float32(0),
+		"float64": float64(0),
+		"[]byte":  []byte(nil),
+	} {
+		types.add(name, prototype)
+	}
+
+	RegisterType("act.Pid", new(Pid))
+	RegisterType("act.Down", Down{})
+	RegisterType("act.Exit", Exit{})
+	RegisterType("act.GsTimeout", GsTimeout(0))
+	RegisterType("act.GsInitOk", GsInitOk)
+	RegisterType("act.GsInitOkTimeout", new(GsInitOkTimeout))
+	RegisterType("act.GsInitStop", new(GsInitStop))
+	RegisterType("act.GsCastNoReply", GsCastNoReply)
+	RegisterType("act.GsCastNoReplyTimeout", new(GsCastNoReplyTimeout))
+	RegisterType("act.GsCastStop", new(GsCastStop))
+	RegisterType("act.GsCastDefer", GsCastDefer)
+	RegisterType("act.GsCallReplyOk", GsCallReplyOk)
+	RegisterType("act.GsCallReply", new(GsCallReply))
+	RegisterType("act.GsCallReplyTimeout", new(GsCallReplyTimeout))
+	RegisterType("act.GsCallNoReply", GsCallNoReply)
+	RegisterType("act.GsCallNoReplyTimeout", new(GsCallNoReplyTimeout))
+	RegisterType("act.GsCallStop", new(GsCallStop))
+	RegisterType("act.GsCallDefer", GsCallDefer)
+	RegisterType("act.frame", new(frame))
+}
+
+//
+// RegisterType registers the type of prototype with the name for all codecs.
+// Terms of the type are decoded as values of the type of prototype, pointers
+// for pointer prototypes
+//
+func RegisterType(name string, prototype interface{}) {
+	gob.RegisterName(name, prototype)
+	types.add(name, prototype)
+}
+
+//
+// RegisterCodec registers the codec by name, it can be chosen by SetCodec
+// on both sides of the node connection
+//
+func RegisterCodec(c Codec) {
+	types.mu.Lock()
+	types.codecs[c.Name()] = c
+	types.mu.Unlock()
+}
+
+//
+// SetCodec sets the codec of the default environment
+//
+func SetCodec(c Codec) {
+	env.SetCodec(c)
+}
+
+//
+// SetCodec sets the codec of messages sent to nodes connected by Connect.
+// The codec must be registered on the listening node. GobCodec is used by
+// default
+//
+func (a *Act) SetCodec(c Codec) {
+	RegisterCodec(c)
+	a.codec.Store(&codecBox{c})
+}
+
+type codecBox struct {
+	codec Codec
+}
+
+func (a *Act) getCodec() Codec {
+	if box := a.codec.Load(); box != nil {
+		return box.codec
+	}
+
+	return GobCodec
+}
+
+// ---------------------------------------------------------------------------
+func (r *typeRegistry) add(name string, prototype interface{}) {
+	t := reflect.TypeOf(prototype)
+
+	r.mu.Lock()
+	r.byName[name] = t
+	r.names[t] = name
+	r.mu.Unlock()
+}
+
+func (r *typeRegistry) name(t reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.names[t]
+
+	return name, ok
+}
+
+func (r *typeRegistry) typeOf(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.byName[name]
+
+	return t, ok
+}
+
+func (r *typeRegistry) codec(name string) (Codec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.codecs[name]
+
+	return c, ok
+}
+
+// ---------------------------------------------------------------------------
+type gobCodec struct{}
+
+//
+// gobTerm wraps the term, gob encodes interface values in fields only
+//
+type gobTerm struct {
+	T Term
+}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Marshal(t Term) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(gobTerm{t}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(b []byte) (Term, error) {
+	var t gobTerm
+
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&t); err != nil {
+		return nil, err
+	}
+
+	return t.T, nil
+}
+
+// ---------------------------------------------------------------------------
+type jsonCodec struct{}
+
+//
+// jsonTerm encodes the term as {"type": name, "value": value}, nil as null
+//
+type jsonTerm struct {
+	Term
+}
+
+type jsonEnvelope struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(t Term) ([]byte, error) {
+	return json.Marshal(jsonTerm{t})
+}
+
+func (jsonCodec) Unmarshal(b []byte) (Term, error) {
+	var t jsonTerm
+
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, err
+	}
+
+	return t.Term, nil
+}
+
+func (t jsonTerm) MarshalJSON() ([]byte, error) {
+	if t.Term == nil {
+		return []byte("null"), nil
+	}
+
+	name, ok := types.name(reflect.TypeOf(t.Term))
+	if !ok {
+		return nil, fmt.Errorf("type %T is not registered", t.Term)
+	}
+
+	value, err := json.Marshal(t.Term)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonEnvelope{name, value})
+}
+
+func (t *jsonTerm) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		t.Term = nil
+		return nil
+	}
+
+	var e jsonEnvelope
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+
+	typ, ok := types.typeOf(e.Type)
+	if !ok {
+		return fmt.Errorf("type %q is not registered", e.Type)
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		v := reflect.New(typ.Elem())
+		if err := json.Unmarshal(e.Value, v.Interface()); err != nil {
+			return err
+		}
+		t.Term = v.Interface()
+		return nil
+	}
+
+	v := reflect.New(typ)
+	if err := json.Unmarshal(e.Value, v.Interface()); err != nil {
+		return err
+	}
+	t.Term = v.Elem().Interface()
+
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Types with error and term fields are encoded through wire types: errors
+// as wireError, terms with the type name for JSON
+
+type wireDown struct {
+	Ref    MonitorRef
+	Pid    *Pid
+	Reason *wireError
+}
+
+type wireExit struct {
+	Pid    *Pid
+	Reason *wireError
+}
+
+type wireStop struct {
+	Reason *wireError
+	Reply  jsonTerm
+}
+
+type wireReply struct {
+	Reply   jsonTerm
+	Timeout uint32 `json:",omitempty"`
+}
+
+type wireFrame struct {
+	Type     frameType
+	Ref      uint64
+	To       uint64
+	Node     string
+	Codec    string
+	Prefix   string
+	Name     jsonTerm
+	Data     jsonTerm
+	Err      *wireError
+	Prio     Priority
+	Deadline time.Time
+}
+
+func gobEncode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(v)
+
+	return buf.Bytes(), err
+}
+
+func gobDecode(b []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+func (d Down) wire() wireDown {
+	return wireDown{d.Ref, d.Pid, encodeError(d.Reason)}
+}
+
+func (d *Down) unwire(w wireDown) {
+	*d = Down{w.Ref, w.Pid, decodeError(w.Reason)}
+}
+
+func (e Exit) wire() wireExit {
+	return wireExit{e.Pid, encodeError(e.Reason)}
+}
+
+func (e *Exit) unwire(w wireExit) {
+	*e = Exit{w.Pid, decodeError(w.Reason)}
+}
+
+//
+// GobEncode encodes Down with the reason as error message and kind
+//
+func (d Down) GobEncode() ([]byte, error) {
+	return gobEncode(d.wire())
+}
+
+//
+// GobDecode decodes Down
+//
+func (d *Down) GobDecode(b []byte) error {
+	var w wireDown
+	if err := gobDecode(b, &w); err != nil {
+		return err
+	}
+	d.unwire(w)
+
+	return nil
+}
+
+//
+// MarshalJSON encodes Down with the reason as error message and kind
+//
+func (d Down) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.wire())
+}
+
+//
+// UnmarshalJSON decodes Down
+//
+func (d *Down) UnmarshalJSON(b []byte) error {
+	var w wireDown
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	d.unwire(w)
+
+	return nil
+}
+
+//
+// GobEncode encodes Exit with the reason as error message and kind
+//
+func (e Exit) GobEncode() ([]byte, error) {
+	return gobEncode(e.wire())
+}
+
+//
+// GobDecode decodes Exit
+//
+func (e *Exit) GobDecode(b []byte) error {
+	var w wireExit
+	if err := gobDecode(b, &w); err != nil {
+		return err
+	}
+	e.unwire(w)
+
+	return nil
+}
+
+//
+// MarshalJSON encodes Exit with the reason as error message and kind
+//
+func (e Exit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.wire())
+}
+
+//
+// UnmarshalJSON decodes Exit
+//
+func (e *Exit) UnmarshalJSON(b []byte) error {
+	var w wireExit
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	e.unwire(w)
+
+	return nil
+}
+
+//
+// GobEncode encodes the stop reason as error message and kind
+//
+func (s GsInitStop) GobEncode() ([]byte, error) {
+	return gobEncode(wireStop{Reason: encodeError(s.Reason)})
+}
+
+//
+// GobDecode decodes the stop reason
+//
+func (s *GsInitStop) GobDecode(b []byte) error {
+	var w wireStop
+	if err := gobDecode(b, &w); err != nil {
+		return err
+	}
+	s.Reason = decodeError(w.Reason)
+
+	return nil
+}
+
+//
+// MarshalJSON encodes the stop reason as error message and kind
+//
+func (s GsInitStop) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireStop{Reason: encodeError(s.Reason)})
+}
+
+//
+// UnmarshalJSON decodes the stop reason
+//
+func (s *GsInitStop) UnmarshalJSON(b []byte) error {
+	var w wireStop
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	s.Reason = decodeError(w.Reason)
+
+	return nil
+}
+
+//
+// GobEncode encodes the stop reason as error message and kind
+//
+func (s GsCastStop) GobEncode() ([]byte, error) {
+	return gobEncode(wireStop{Reason: encodeError(s.Reason)})
+}
+
+//
+// GobDecode decodes the stop reason
+//
+func (s *GsCastStop) GobDecode(b []byte) error {
+	var w wireStop
+	if err := gobDecode(b, &w); err != nil {
+		return err
+	}
+	s.Reason = decodeError(w.Reason)
+
+	return nil
+}
+
+//
+// MarshalJSON encodes the stop reason as error message and kind
+//
+func (s GsCastStop) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireStop{Reason: encodeError(s.Reason)})
+}
+
+//
+// UnmarshalJSON decodes the stop reason
+//
+func (s *GsCastStop) UnmarshalJSON(b []byte) error {
+	var w wireStop
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	s.Reason = decodeError(w.Reason)
+
+	return nil
+}
+
+//
+// GobEncode encodes the stop reason as error message and kind and the reply
+//
+func (s GsCallStop) GobEncode() ([]byte, error) {
+	return gobEncode(wireStop{encodeError(s.Reason), jsonTerm{s.Reply}})
+}
+
+//
+// GobDecode decodes the stop reason and the reply
+//
+func (s *GsCallStop) GobDecode(b []byte) error {
+	var w wireStop
+	if err := gobDecode(b, &w); err != nil {
+		return err
+	}
+	*s = GsCallStop{decodeError(w.Reason), w.Reply.Term}
+
+	return nil
+}
+
+//
+// MarshalJSON encodes the stop reason as error message and kind and
+// the reply with its type name
+//
+func (s GsCallStop) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireStop{encodeError(s.Reason), jsonTerm{s.Reply}})
+}
+
+//
+// UnmarshalJSON decodes the stop reason and the reply
+//
+func (s *GsCallStop) UnmarshalJSON(b []byte) error {
+	var w wireStop
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	*s = GsCallStop{decodeError(w.Reason), w.Reply.Term}
+
+	return nil
+}
+
+//
+// MarshalJSON encodes the reply with its type name
+//
+func (r GsCallReply) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireReply{Reply: jsonTerm{r.Reply}})
+}
+
+//
+// UnmarshalJSON decodes the reply
+//
+func (r *GsCallReply) UnmarshalJSON(b []byte) error {
+	var w wireReply
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	*r = GsCallReply{w.Reply.Term}
+
+	return nil
+}
+
+//
+// MarshalJSON encodes the reply with its type name and the timeout
+//
+func (r GsCallReplyTimeout) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireReply{jsonTerm{r.Reply}, r.Timeout})
+}
+
+//
+// UnmarshalJSON decodes the reply and the timeout
+//
+func (r *GsCallReplyTimeout) UnmarshalJSON(b []byte) error {
+	var w wireReply
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+	*r = GsCallReplyTimeout{w.Reply.Term, w.Timeout}
+
+	return nil
+}
+
+//
+// MarshalJSON encodes the pid as node name and id
+//
+func (pid *Pid) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wirePid{pid.Node(), pid.Id()})
+}
+
+//
+// UnmarshalJSON decodes the pid. The decoded pid is replaced with the pid
+// of the process by the node transport
+//
+func (pid *Pid) UnmarshalJSON(b []byte) error {
+	var w wirePid
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+
+	pid.id = w.Id
+	pid.wireNode = w.Node
+
+	return nil
+}
+
+func (f *frame) MarshalJSON() ([]byte, error) {
+	return json.Marshal(wireFrame{
+		f.Type, f.Ref, f.To, f.Node, f.Codec, f.Prefix,
+		jsonTerm{f.Name}, jsonTerm{f.Data}, f.Err, f.Prio, f.Deadline,
+	})
+}
+
+func (f *frame) UnmarshalJSON(b []byte) error {
+	var w wireFrame
+	if err := json.Unmarshal(b, &w); err != nil {
+		return err
+	}
+
+	*f = frame{
+		w.Type, w.Ref, w.To, w.Node, w.Codec, w.Prefix,
+		w.Name.Term, w.Data.Term, w.Err, w.Prio, w.Deadline,
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package act
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type codecMsg struct {
+	Key   string
+	Value int
+	Pids  []*Pid
+}
+
+func init() {
+	RegisterType("act.codecMsg", codecMsg{})
+}
+
+func roundTrip(t *testing.T, c Codec, term Term) Term {
+	t.Helper()
+
+	b, err := c.Marshal(term)
+	if err != nil {
+		t.Fatalf("%s: marshal %#v: %v", c.Name(), term, err)
+	}
+
+	r, err := c.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("%s: unmarshal %s: %v", c.Name(), b, err)
+	}
+
+	return r
+}
+
+func TestCodecTerms(t *testing.T) {
+	terms := []Term{
+		nil,
+		"hello",
+		42,
+		int64(-7),
+		uint32(7),
+		3.5,
+		true,
+		[]byte("bytes"),
+		codecMsg{"key", 1, nil},
+		GsInitOk,
+		GsCastNoReply,
+		GsCallReplyOk,
+		GsCallNoReply,
+		GsCastDefer,
+		GsCallDefer,
+		GsTimeout(0),
+		&GsInitOkTimeout{100},
+		&GsCastNoReplyTimeout{100},
+		&GsCallReply{"reply"},
+		&GsCallReply{42},
+		&GsCallReply{codecMsg{"key", 1, nil}},
+		&GsCallReply{nil},
+		&GsCallReplyTimeout{"reply", 100},
+		&GsCallReplyTimeout{uint32(7), 100},
+		&GsCallReplyTimeout{codecMsg{"key", 2, nil}, 100},
+		&GsCallNoReplyTimeout{100},
+	}
+
+	for _, c := range []Codec{GobCodec, JSONCodec} {
+		for _, term := range terms {
+			if r := roundTrip(t, c, term); !reflect.DeepEqual(r, term) {
+				t.Errorf("%s: want %#v, got %#v", c.Name(), term, r)
+			}
+		}
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	for _, c := range []Codec{GobCodec, JSONCodec} {
+		r := roundTrip(t, c, &GsCallStop{Shutdown, "bye"})
+		if stop, ok := r.(*GsCallStop); !ok ||
+			stop.Reason != Shutdown || stop.Reply != "bye" {
+			t.Errorf("%s: bad GsCallStop %#v", c.Name(), r)
+		}
+
+		r = roundTrip(t, c, &GsCallStop{Normal, 42})
+		if stop, ok := r.(*GsCallStop); !ok || stop.Reply != 42 {
+			t.Errorf("%s: bad GsCallStop %#v", c.Name(), r)
+		}
+
+		r = roundTrip(t, c, &GsCastStop{errors.New("custom")})
+		if stop, ok := r.(*GsCastStop); !ok || stop.Reason.Error() != "custom" {
+			t.Errorf("%s: bad GsCastStop %#v", c.Name(), r)
+		}
+
+		r = roundTrip(t, c, &GsInitStop{GsNoProcError})
+		if stop, ok := r.(*GsInitStop); !ok || !IsNoProcError(stop.Reason) {
+			t.Errorf("%s: bad GsInitStop %#v", c.Name(), r)
+		}
+
+		r = roundTrip(t, c, Exit{Reason: Killed})
+		if exit, ok := r.(Exit); !ok || exit.Reason != Killed {
+			t.Errorf("%s: bad Exit %#v", c.Name(), r)
+		}
+	}
+}
+
+func TestCodecPids(t *testing.T) {
+	a := NewEnv()
+	defer a.Shutdown(contextTimeout(t))
+
+	pid, err := a.Spawn(&gsOrder{release: make(chan struct{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []Codec{GobCodec, JSONCodec} {
+		r := roundTrip(t, c, Down{Ref: 5, Pid: pid, Reason: Normal})
+		down, ok := a.resolvePids(r).(Down)
+		if !ok || down.Ref != 5 || down.Pid != pid || down.Reason != Normal {
+			t.Errorf("%s: bad Down %#v", c.Name(), r)
+		}
+
+		r = roundTrip(t, c, codecMsg{Pids: []*Pid{pid, nil}})
+		msg, ok := a.resolvePids(r).(codecMsg)
+		if !ok || len(msg.Pids) != 2 || msg.Pids[0] != pid || msg.Pids[1] != nil {
+			t.Errorf("%s: bad message %#v", c.Name(), r)
+		}
+	}
+}
+
+func TestCodecNotRegistered(t *testing.T) {
+	type unknown struct{ A int }
+
+	for _, c := range []Codec{GobCodec, JSONCodec} {
+		if _, err := c.Marshal(unknown{1}); err == nil {
+			t.Errorf("%s: want error for unregistered type", c.Name())
+		}
+	}
+
+	if _, err := JSONCodec.Unmarshal([]byte(`{"type":"nope","value":1}`)); err == nil {
+		t.Error("want error for unknown type name")
+	}
+}
+
+func TestNodeJSONCodec(t *testing.T) {
+	a := NewEnv()
+	b := NewEnv()
+	defer func() {
+		b.Shutdown(contextTimeout(t))
+		a.Shutdown(contextTimeout(t))
+	}()
+
+	b.SetCodec(JSONCodec)
+
+	if err := a.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.SpawnOpts(&gsEcho{}, &Opts{Name: "echo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := b.Connect(a.NodeName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid := remoteEcho(t, node)
+
+	r, err := pid.Call(codecMsg{Key: "key", Value: 1})
+	if err != nil || !reflect.DeepEqual(r, codecMsg{Key: "key", Value: 1}) {
+		t.Errorf("want message, got %#v, %v", r, err)
+	}
+
+	r, err = pid.Call("self")
+	if err != nil || r != pid {
+		t.Errorf("want remote pid, got %#v, %v", r, err)
+	}
+
+	if _, err := pid.Call("error"); err == nil || err.Error() != "echo error" {
+		t.Errorf("want echo error, got %v", err)
+	}
+}
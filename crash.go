@@ -0,0 +1,115 @@
+package act
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+//
+// CrashReport describes the process crashed by panic
+//
+type CrashReport struct {
+	Pid     *Pid
+	Id      uint64
+	Prefix  string
+	Name    interface{}
+	Panic   interface{}
+	Stack   []byte      // full stack of the crashed goroutine
+	Message Term        // message being handled, nil if crashed out of handler
+	State   interface{} // result of FormatState if implemented
+	Time    time.Time
+}
+
+//
+// StateFormatter may be implemented by GenServer to add a snapshot of
+// the process state to CrashReport
+//
+type StateFormatter interface {
+	FormatState() interface{}
+}
+
+//
+// CrashHandler receives reports of crashed processes
+//
+type CrashHandler func(report *CrashReport)
+
+type crashHandlerBox struct {
+	handler CrashHandler
+}
+
+//
+// SetCrashHandler sets the crash handler of the default environment
+//
+func SetCrashHandler(h CrashHandler) {
+	env.SetCrashHandler(h)
+}
+
+//
+// SetCrashHandler sets the crash handler of the environment. The handler is
+// called in the crashed process before Terminate. nil restores the default
+// handler logging the report with Error level
+//
+func (a *Act) SetCrashHandler(h CrashHandler) {
+	a.crashHandler.Store(&crashHandlerBox{h})
+}
+
+// ---------------------------------------------------------------------------
+func (pid *Pid) crashReport(
+	gs GenServer,
+	r interface{},
+	message Term) *CrashReport {
+
+	report := &CrashReport{
+		Pid:     pid,
+		Id:      pid.Id(),
+		Prefix:  pid.prefix,
+		Name:    pid.name,
+		Panic:   r,
+		Stack:   debug.Stack(),
+		Message: message,
+		Time:    time.Now(),
+	}
+
+	if f, ok := gs.(StateFormatter); ok {
+		report.State = formatState(f)
+	}
+
+	return report
+}
+
+//
+// formatState returns the state snapshot, the panic of broken state is
+// returned as the state
+//
+func formatState(f StateFormatter) (state interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			state = fmt.Sprintf("FormatState panic: %#v", r)
+		}
+	}()
+
+	return f.FormatState()
+}
+
+//
+// reportCrash calls the crash handler of the environment
+//
+func (pid *Pid) reportCrash(report *CrashReport) {
+	defer func() {
+		if r := recover(); r != nil {
+			pid.log().Error("crash handler panic",
+				pid.logFields("panic", fmt.Sprintf("%#v", r))...)
+		}
+	}()
+
+	if box := pid.env.crashHandler.Load(); box != nil && box.handler != nil {
+		box.handler(report)
+		return
+	}
+
+	pid.log().Error("crash", pid.logFields(
+		"panic", fmt.Sprintf("%#v", report.Panic),
+		"message", fmt.Sprintf("%#v", report.Message),
+		"stack", string(report.Stack))...)
+}
@@ -0,0 +1,113 @@
+package act
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type gsCrash struct {
+	GenServerImpl
+	counter int
+}
+
+func (s *gsCrash) HandleCast(req Term) Term {
+	s.counter++
+	if req == "panic" {
+		panic("boom")
+	}
+
+	return GsCastNoReply
+}
+
+func (s *gsCrash) FormatState() interface{} {
+	if s.counter > 10 {
+		panic("broken state")
+	}
+
+	return s.counter
+}
+
+func crashReportOf(t *testing.T, a *Act, s GenServer, msgs ...Term) *CrashReport {
+	reports := make(chan *CrashReport, 1)
+	a.SetCrashHandler(func(report *CrashReport) {
+		reports <- report
+	})
+
+	pid, err := a.SpawnOpts(s, &Opts{Prefix: "crash", Name: "report"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range msgs {
+		pid.Cast(m)
+	}
+
+	select {
+	case report := <-reports:
+		return report
+	case <-time.After(time.Second):
+		t.Fatal("no crash report")
+	}
+
+	return nil
+}
+
+func TestCrashReport(t *testing.T) {
+	a := NewEnv()
+	report := crashReportOf(t, a, new(gsCrash), "ok", "panic")
+
+	if report.Id == 0 || report.Pid.Id() != report.Id ||
+		report.Prefix != "crash" || report.Name != "report" {
+		t.Errorf("bad identity: %+v", report)
+	}
+	if report.Panic != "boom" || report.Message != "panic" {
+		t.Errorf("bad panic or message: %#v, %#v", report.Panic, report.Message)
+	}
+	if report.State != 2 {
+		t.Errorf("want state 2, got %#v", report.State)
+	}
+	if report.Time.IsZero() {
+		t.Error("time must be set")
+	}
+
+	if !bytes.Contains(report.Stack, []byte("(*gsCrash).HandleCast")) {
+		t.Errorf("stack must contain the handler: %s", report.Stack)
+	}
+	if n := bytes.Count(report.Stack, []byte("\ngoroutine ")); n != 0 ||
+		!bytes.HasPrefix(report.Stack, []byte("goroutine ")) {
+		t.Errorf("stack must contain only crashed goroutine: %s", report.Stack)
+	}
+}
+
+func TestCrashReportBrokenState(t *testing.T) {
+	a := NewEnv()
+
+	s := &gsCrash{counter: 10}
+	report := crashReportOf(t, a, s, "panic")
+
+	state, ok := report.State.(string)
+	if !ok || !bytes.Contains([]byte(state), []byte("broken state")) {
+		t.Errorf("want panic of FormatState, got %#v", report.State)
+	}
+}
+
+func TestCrashHandlerPanic(t *testing.T) {
+	a := NewEnv()
+	a.SetLogger(new(testLogger))
+	a.SetCrashHandler(func(report *CrashReport) {
+		panic("handler")
+	})
+
+	pid, err := a.Spawn(new(gsCrash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid.Cast("panic")
+
+	time.Sleep(50 * time.Millisecond)
+
+	if pid.IsAlive() {
+		t.Error("process must be stopped")
+	}
+}
@@ -1,9 +1,9 @@
 package act
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"runtime"
 	"time"
 )
 
@@ -27,6 +27,31 @@ func IsNoProcError(err error) bool {
 	return false
 }
 
+//
+// Call timeout error
+//
+type gsTimeoutError int
+
+func (e gsTimeoutError) Error() string {
+	return "timeout"
+}
+
+// Is reports the timeout error matches context.DeadlineExceeded
+func (e gsTimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+//
+// IsTimeoutError checks if error is of type of gsTimeoutError
+//
+func IsTimeoutError(err error) bool {
+	if _, ok := err.(gsTimeoutError); ok {
+		return true
+	}
+
+	return false
+}
+
 //
 // gen_server's return types
 //
@@ -60,7 +85,7 @@ type GsInitOkTimeout struct {
 // the process must be stopped
 //
 type GsInitStop struct {
-	Reason string
+	Reason error
 }
 
 // ---------------------------------------------------------------------------
@@ -73,6 +98,8 @@ type GsInitStop struct {
 //
 type gsCastNoReply int
 
+type gsCastDefer int
+
 //
 // GsCastNoReplyTimeout is returned from the HandleCast callback to indicate
 // that an inactivity timer must be set
@@ -86,7 +113,7 @@ type GsCastNoReplyTimeout struct {
 // the process must be stopped
 //
 type GsCastStop struct {
-	Reason string
+	Reason error
 }
 
 // ---------------------------------------------------------------------------
@@ -121,6 +148,8 @@ type GsCallReplyTimeout struct {
 
 type gsCallNoReply int
 
+type gsCallDefer int
+
 //
 // GsCallNoReplyTimeout is returned from the HandleCall callback to indicate
 // that an inactivity timer must be set. Result to caller returned with Reply()
@@ -134,7 +163,7 @@ type GsCallNoReplyTimeout struct {
 // the process must be stopped
 //
 type GsCallStop struct {
-	Reason string
+	Reason error
 	Reply  Term
 }
 
@@ -156,6 +185,16 @@ const (
 	// GsNoProcError can be returned from Cast or Call if process identificated
 	// by pid is not exists
 	GsNoProcError gsNoProcError = 5
+	// GsTimeoutError is returned from CallTimeout if the process does not
+	// reply in time
+	GsTimeoutError gsTimeoutError = 6
+	// GsCastDefer is returned from HandleCast callback to defer the message.
+	// Deferred messages are handled again, in order, after the next message
+	// that is not deferred
+	GsCastDefer gsCastDefer = 7
+	// GsCallDefer is returned from HandleCall callback to defer the message
+	// like GsCastDefer
+	GsCallDefer gsCallDefer = 8
 )
 
 //
@@ -165,6 +204,7 @@ const (
 // Cast arg
 type genReq struct {
 	data Term
+	ctx  context.Context
 }
 
 // From type for Reply() method
@@ -174,11 +214,12 @@ type From chan<- Term
 type genCallReq struct {
 	data      Term
 	replyChan From
+	ctx       context.Context
 }
 
 // Stop arg
 type stopReq struct {
-	reason    string
+	reason    error
 	replyChan chan<- bool
 }
 
@@ -189,7 +230,7 @@ type GenServer interface {
 	Init(args ...interface{}) (result Term)
 	HandleCall(req Term, from From) (result Term)
 	HandleCast(req Term) (result Term)
-	Terminate(reason string)
+	Terminate(reason error)
 
 	// private
 	setPid(pid *Pid)
@@ -197,8 +238,19 @@ type GenServer interface {
 	setName(name interface{})
 }
 
+//
+// GenServerCtx is an optional interface of GenServer. If the process
+// implements it, HandleCallCtx and HandleCastCtx are called instead of
+// HandleCall and HandleCast with the context passed to CallContext and
+// CastContext, or context.Background() for Call and Cast
+//
+type GenServerCtx interface {
+	HandleCallCtx(ctx context.Context, req Term, from From) (result Term)
+	HandleCastCtx(ctx context.Context, req Term) (result Term)
+}
+
 // GenServerLoop executes during whole time of process life.
-// It receives incoming messages from channels and handle it
+// It receives incoming messages from the mailbox and handle it
 // using methods of implementation
 func (a *Act) GenServerLoop(
 	gs GenServer,
@@ -211,43 +263,74 @@ func (a *Act) GenServerLoop(
 	var timer *Timer
 	var replyCall chan<- Term
 	var replyStop chan<- bool
+	var saved, replay []interface{} // deferred messages
 	inCall := false
 	inStop := false
 	inTerminate := false
+	initDone := false
+	var exitReason error = Normal
+	var message Term // message being handled
+	var started time.Time
+
+	hooks := pid.hooks()
+
+	gsCtx, withCtx := gs.(GenServerCtx)
 
 	defer func() {
 
-		a.UnregisterPrefix(prefix, name)
+		pid.unregisterNames()
+		pid.env.procs.remove(pid)
+		pid.leaveGroups()
+		pid.unregisterGlobals()
 		timer.Stop()
-		pid.flushMessages(prefix, name)
-		pid.closeChannels(prefix, name)
+		pid.closeMailbox(append(saved, replay...))
 
 		if r := recover(); r != nil {
 
-			now := time.Now().Truncate(time.Microsecond)
+			report := pid.crashReport(gs, r, message)
+			pid.reportCrash(report)
+			if hooks != nil {
+				pid.callHook("OnCrash", func() { hooks.OnCrash(report) })
+			}
 
-			fmt.Printf("%s pid #%d/%s/%v: GenServer recovered: %#v\n",
-				now, pid.Id(), prefix, name, r)
+			crash := &Crash{Panic: r, Stack: report.Stack}
+			exitReason = crash
 
-			trace := make([]byte, 512)
-			count := runtime.Stack(trace, true)
-			fmt.Printf("%s pid #%d/%s/%s: Stack of %d bytes: %s\n",
-				now, pid.Id(), prefix, name, count, trace)
+			// Init crashed, Spawn returns the crash
+			if !initDone {
+				initChan <- crash
+				inTerminate = true
+			}
 
 			if !inTerminate {
 				inTerminate = true
-				gs.Terminate(fmt.Sprintf("crashed: %#v", r))
+				gs.Terminate(crash)
 			}
 
 			if inCall {
-				replyCall <- fmt.Errorf("crashed: %#v", r)
+				Reply(replyCall, crash)
 			}
 
-			if inStop {
-				replyStop <- true
-			}
 		}
 
+		if isAbnormal(exitReason) {
+			pid.log().Warn("terminate", pid.logFields("reason", exitReason)...)
+		} else {
+			pid.log().Info("terminate", pid.logFields("reason", exitReason)...)
+		}
+
+		if hooks != nil {
+			pid.callHook("OnTerminate", func() {
+				hooks.OnTerminate(pid, exitReason)
+			})
+		}
+
+		pid.exit(exitReason)
+
+		// reply to stop request when the process is completely stopped
+		if inStop {
+			replyStop <- true
+		}
 	}()
 
 	gs.setPid(pid)
@@ -255,8 +338,7 @@ func (a *Act) GenServerLoop(
 	gs.setName(name)
 
 	result := gs.Init(args...)
-
-	nLog("init result: %#v", result)
+	initDone = true
 
 	switch r := result.(type) {
 
@@ -265,14 +347,16 @@ func (a *Act) GenServerLoop(
 
 	case *GsInitOkTimeout:
 		initChan <- result
-		timer = pid.SendAfterWithStop(gsTimeout, r.Timeout)
+		timer = pid.timeoutAfter(r.Timeout)
 
 	case *GsInitStop:
-		initChan <- result
+		exitReason = reasonOrNormal(r.Reason)
+		initChan <- exitReason
 		return
 
 	default:
-		initChan <- fmt.Errorf("Init bad reply: %#v", r)
+		exitReason = fmt.Errorf("Init %w: %#v", ErrBadReply, r)
+		initChan <- exitReason
 		return
 	}
 
@@ -281,142 +365,257 @@ func (a *Act) GenServerLoop(
 		inCall = false
 		inStop = false
 		inTerminate = false
-
-		select {
-		case m := <-pid.inChan:
-
-			timer.Stop()
-
-			// channel closed
-			if m == nil {
+		hooks = pid.hooks()
+
+		//
+		// system messages first, then deferred messages to retry,
+		// then high and normal priority messages
+		//
+		m, ok := pid.mbox.tryPop(prioritySystem)
+		if !ok && len(replay) > 0 {
+			m, ok = replay[0], true
+			replay = replay[1:]
+		}
+		if !ok {
+			if m, ok = pid.mbox.pop(); !ok {
+				// mailbox closed
 				return
 			}
+		}
 
-			switch m := m.(type) {
+		timer.Stop()
+		pid.timeoutAt.Store(0)
+		pid.lastActivity.Store(time.Now().UnixNano())
 
-			// Call
-			case *genCallReq:
+		deferred := false
 
-				inCall = true
-				replyCall = m.replyChan
+		switch m := m.(type) {
 
-				nLog("call message: %#v", m)
-				result := gs.HandleCall(m.data, m.replyChan)
-				nLog("call result: %#v", result)
+		// Call
+		case *genCallReq:
 
-				inCall = false
+			inCall = true
+			replyCall = m.replyChan
 
-				switch result := result.(type) {
+			pid.logDebug("call", "req", m.data)
+			message = m.data
+			pid.handling(StatusCall)
+			if hooks != nil {
+				pid.callHook("OnMessageIn", func() {
+					hooks.OnMessageIn(pid, m.data)
+				})
+				started = time.Now()
+			}
+			var result Term
+			if withCtx {
+				result = gsCtx.HandleCallCtx(m.ctx, m.data, m.replyChan)
+			} else {
+				result = gs.HandleCall(m.data, m.replyChan)
+			}
+			pid.logDebug("call result", "result", result)
+			if hooks != nil {
+				d := time.Since(started)
+				pid.callHook("OnHandled", func() {
+					hooks.OnHandled(pid, m.data, d, result)
+				})
+			}
+			message = nil
+			pid.handled()
 
-				case *GsCallReply:
-					m.replyChan <- result.Reply
+			inCall = false
 
-				case gsCallReplyOk:
-					m.replyChan <- replyOk
+			switch result := result.(type) {
 
-				case *GsCallReplyTimeout:
-					m.replyChan <- result.Reply
-					timer = pid.SendAfterWithStop(gsTimeout, result.Timeout)
+			case *GsCallReply:
+				Reply(m.replyChan, result.Reply)
 
-				case gsCallNoReply:
+			case gsCallReplyOk:
+				Reply(m.replyChan, replyOk)
 
-				case *GsCallNoReplyTimeout:
-					timer = pid.SendAfterWithStop(gsTimeout, result.Timeout)
+			case *GsCallReplyTimeout:
+				Reply(m.replyChan, result.Reply)
+				timer = pid.timeoutAfter(result.Timeout)
 
-				case *GsCallStop:
-					m.replyChan <- result.Reply
-					inTerminate = true
-					gs.Terminate(result.Reason)
-					return
+			case gsCallNoReply:
 
-				case error:
-					m.replyChan <- result
+			case *GsCallNoReplyTimeout:
+				timer = pid.timeoutAfter(result.Timeout)
 
-				default:
-					reply := fmt.Sprintf("HandleCall bad reply: %#v", result)
-					m.replyChan <- errors.New(reply)
-					inTerminate = true
-					gs.Terminate(reply)
-					return
-				}
+			case gsCallDefer:
+				deferred = true
 
-			// Cast
-			case *genReq:
+			case *GsCallStop:
+				Reply(m.replyChan, result.Reply)
+				exitReason = reasonOrNormal(result.Reason)
+				inTerminate = true
+				gs.Terminate(exitReason)
+				return
 
-				nLog("cast message: %#v", m)
-				result := gs.HandleCast(m.data)
-				nLog("cast result: %#v", result)
+			case error:
+				Reply(m.replyChan, result)
 
-				switch result := result.(type) {
+			default:
+				exitReason = fmt.Errorf("HandleCall %w: %#v",
+					ErrBadReply, result)
+				Reply(m.replyChan, exitReason)
+				inTerminate = true
+				gs.Terminate(exitReason)
+				return
+			}
 
-				case gsCastNoReply:
+		// Cast
+		case *genReq:
+
+			pid.logDebug("cast", "req", m.data)
+			message = m.data
+			pid.handling(StatusCast)
+			if hooks != nil {
+				pid.callHook("OnMessageIn", func() {
+					hooks.OnMessageIn(pid, m.data)
+				})
+				started = time.Now()
+			}
+			var result Term
+			if withCtx {
+				result = gsCtx.HandleCastCtx(m.ctx, m.data)
+			} else {
+				result = gs.HandleCast(m.data)
+			}
+			pid.logDebug("cast result", "result", result)
+			if hooks != nil {
+				d := time.Since(started)
+				pid.callHook("OnHandled", func() {
+					hooks.OnHandled(pid, m.data, d, result)
+				})
+			}
+			message = nil
+			pid.handled()
 
-				case *GsCastNoReplyTimeout:
-					timer = pid.SendAfterWithStop(gsTimeout, result.Timeout)
+			switch result := result.(type) {
 
-				case *GsCastStop:
-					inTerminate = true
-					gs.Terminate(result.Reason)
-					return
+			case gsCastNoReply:
 
-				default:
-					inTerminate = true
-					gs.Terminate(
-						fmt.Sprintf("HandleCast bad reply: %#v", result))
-					return
-				}
-			}
+			case *GsCastNoReplyTimeout:
+				timer = pid.timeoutAfter(result.Timeout)
 
-		// Stop
-		case m := <-pid.stopChan:
+			case gsCastDefer:
+				deferred = true
 
-			timer.Stop()
+			case *GsCastStop:
+				exitReason = reasonOrNormal(result.Reason)
+				inTerminate = true
+				gs.Terminate(exitReason)
+				return
 
-			// channel closed
-			if m == nil {
+			default:
+				exitReason = fmt.Errorf("HandleCast %w: %#v",
+					ErrBadReply, result)
+				inTerminate = true
+				gs.Terminate(exitReason)
 				return
 			}
 
+		// Stop
+		case *stopReq:
+
 			inStop = true
 			replyStop = m.replyChan
 
-			nLog("stop message: %s", m.reason)
+			pid.logDebug("stop", "reason", m.reason)
+			exitReason = reasonOrNormal(m.reason)
 			inTerminate = true
-			gs.Terminate(m.reason)
-
-			m.replyChan <- true
-
-			inStop = false
+			gs.Terminate(exitReason)
 
 			return
-		} // select
+		}
+
+		//
+		// deferred messages are retried after the next handled message
+		//
+		if deferred {
+			saved = append(saved, m)
+		} else if len(saved) > 0 {
+			replay = append(saved, replay...)
+			saved = nil
+		}
 	} // for
 }
 
 //
-// Reply sends reply to caller
+// Reply sends reply to caller. Reply never blocks: only the first reply is
+// delivered, a reply to the caller that already gave up is dropped
 //
 func Reply(replyTo From, data Term) {
-	replyTo <- data
+
+	// reply channel is closed if the process stopped
+	defer func() {
+		recover()
+	}()
+
+	select {
+	case replyTo <- data:
+	default:
+	}
 }
 
 //
 // Call makes a synchronous call to the process
 //
 func (pid *Pid) Call(data Term) (reply Term, err error) {
+	return pid.CallContext(context.Background(), data)
+}
 
-	defer func() {
-		if r := recover(); r != nil {
-			reply = nil
-			err = fmt.Errorf("pid #%d: call recovered: %#v", pid.Id(), r)
-		}
-	}()
+//
+// CallTimeout makes a synchronous call to the process and waits for reply at
+// most timeout. GsTimeoutError is returned if the process does not reply in
+// time
+//
+func (pid *Pid) CallTimeout(
+	data Term,
+	timeout time.Duration) (reply Term, err error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reply, err = pid.CallContext(ctx, data)
+	if err == context.DeadlineExceeded {
+		err = GsTimeoutError
+	}
+
+	return
+}
+
+//
+// CallContext makes a synchronous call to the process. It returns ctx.Err()
+// if ctx is done before the process replies. The call is made with priority
+// set by WithPriority
+//
+func (pid *Pid) CallContext(
+	ctx context.Context,
+	data Term) (reply Term, err error) {
+
+	if pid == nil {
+		return nil, GsNoProcError
+	}
+
+	if pid.node != nil {
+		return pid.node.call(ctx, pid, data)
+	}
 
 	var replyTerm Term
 
 	replyChan := make(chan Term, 1)
-	pid.inChan <- &genCallReq{data, replyChan}
-	replyTerm = <-replyChan
+
+	err = pid.mbox.push(ctx, priorityOf(ctx), &genCallReq{data, replyChan, ctx})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case replyTerm = <-replyChan:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 
 	// server stopped
 	if replyTerm == nil {
@@ -436,107 +635,173 @@ func (pid *Pid) Call(data Term) (reply Term, err error) {
 // Cast makes an asynchronous call to the process
 //
 func (pid *Pid) Cast(data Term) (err error) {
+	return pid.CastContext(context.Background(), data)
+}
 
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("pid #%d: cast recovered: %#v", pid.Id(), r)
-		}
-	}()
+//
+// CastContext makes an asynchronous call to the process. The ctx is passed
+// to HandleCastCtx. It returns ctx.Err() if ctx is done before the message
+// is put to the process mailbox. The cast is made with priority set by
+// WithPriority
+//
+func (pid *Pid) CastContext(ctx context.Context, data Term) error {
 
-	pid.inChan <- &genReq{data}
+	if err := pid.castContext(ctx, data); !errors.Is(err, errDropped) {
+		return err
+	}
 
 	return nil
 }
 
+//
+// castContext is CastContext returning errDropped if the message is dropped
+// by the mailbox policy
+//
+func (pid *Pid) castContext(ctx context.Context, data Term) error {
+
+	if pid == nil {
+		return GsNoProcError
+	}
+
+	if pid.node != nil {
+		return pid.node.cast(ctx, pid, data, true)
+	}
+
+	return pid.mbox.push(ctx, priorityOf(ctx), &genReq{data, ctx})
+}
+
+//
+// TryCast makes an asynchronous call to the process without waiting. It
+// returns ErrMailboxFull if there is no room in the process mailbox
+//
+func (pid *Pid) TryCast(data Term) error {
+
+	if pid == nil {
+		return GsNoProcError
+	}
+
+	ctx := context.Background()
+
+	if pid.node != nil {
+		err := pid.node.cast(ctx, pid, data, false)
+		if errors.Is(err, errDropped) {
+			return ErrMailboxFull
+		}
+		return err
+	}
+
+	return pid.mbox.tryPush(PriorityNormal, &genReq{data, ctx})
+}
+
+//
+// CastTimeout makes an asynchronous call to the process. It waits at most
+// timeout for room in the process mailbox and returns ErrMailboxFull after
+//
+func (pid *Pid) CastTimeout(data Term, timeout time.Duration) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := pid.castContext(ctx, data)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errDropped) {
+		return ErrMailboxFull
+	}
+
+	return err
+}
+
+//
+// castSystem sends the message with system priority
+//
+func (pid *Pid) castSystem(data Term) error {
+
+	if pid == nil {
+		return GsNoProcError
+	}
+
+	ctx := context.Background()
+
+	if pid.node != nil {
+		return pid.node.cast(ctx, pid, data, false)
+	}
+
+	return pid.mbox.push(ctx, prioritySystem, &genReq{data, ctx})
+}
+
 //
 // Stop makes synchronous stop request to the process
 //
 func (pid *Pid) Stop() error {
-	return pid.StopReason("stop")
+	return pid.StopReason(Shutdown)
 }
 
 //
 // StopReason makes synchronous stop request to the process
 // Reason is the reason to stop the process
 //
-func (pid *Pid) StopReason(reason string) (err error) {
+func (pid *Pid) StopReason(reason error) error {
 
-	defer func() {
-		if r := recover(); r != nil {
-			err = fmt.Errorf("pid #%d: stop recovered: %#v", pid.Id(), r)
-		}
-	}()
+	if pid == nil {
+		return GsNoProcError
+	}
+
+	if pid.node != nil {
+		return pid.node.stop(context.Background(), pid, reason)
+	}
+
+	replyChan := make(chan bool, 1)
+
+	err := pid.mbox.push(
+		context.Background(), prioritySystem, &stopReq{reason, replyChan})
+	if err != nil {
+		return err
+	}
 
-	replyChan := make(chan bool)
-	pid.stopChan <- &stopReq{reason, replyChan}
 	<-replyChan
 
 	return nil
 }
 
-// ---------------------------------------------------------------------------
-func (pid *Pid) closeChannels(prefix string, name interface{}) {
+//
+// stopContext stops the process, waits until it is stopped or ctx is done
+//
+func (pid *Pid) stopContext(ctx context.Context, reason error) error {
 
-	defer func() {
-		if r := recover(); r != nil {
-			now := time.Now().Truncate(time.Microsecond)
+	replyChan := make(chan bool, 1)
 
-			fmt.Printf("%s pid #%d/%s/%v: closeChannels recovered: %#v\n",
-				now, pid.Id(), prefix, name, r)
+	err := pid.mbox.push(ctx, prioritySystem, &stopReq{reason, replyChan})
+	if err != nil {
+		return err
+	}
 
-			trace := make([]byte, 512)
-			count := runtime.Stack(trace, true)
-			fmt.Printf("%s pid #%d/%s/%s: closeChannels stack of %d bytes: %s\n",
-				now, pid.Id(), prefix, name, count, trace)
-		}
-	}()
+	select {
+	case <-replyChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 
-	close(pid.inChan)
-	close(pid.stopChan)
+	return nil
 }
 
-func (pid *Pid) flushMessages(prefix string, name interface{}) {
-
-	defer func() {
-		if r := recover(); r != nil {
-			now := time.Now().Truncate(time.Microsecond)
-
-			fmt.Printf("%s pid #%d/%s/%v: flushMessages recovered: %#v\n",
-				now, pid.Id(), prefix, name, r)
-
-			trace := make([]byte, 512)
-			count := runtime.Stack(trace, true)
-			fmt.Printf("%s pid #%d/%s/%s: flushMessages stack of %d bytes: %s\n",
-				now, pid.Id(), prefix, name, count, trace)
-		}
-	}()
+// ---------------------------------------------------------------------------
+//
+// closeMailbox closes the mailbox, callers waiting for reply of pending
+// messages get GsNoProcError
+//
+func (pid *Pid) closeMailbox(pending []interface{}) {
 
-	for len(pid.inChan) > 0 {
-		select {
-		case m := <-pid.inChan:
-			switch m := m.(type) {
-			case *genCallReq:
-				fmt.Printf("%s flushMessages: pid #%d/%s/%s: %#v\n",
-					time.Now().Truncate(time.Microsecond),
-					pid.Id(), prefix, name, m)
-				close(m.replyChan)
-			}
-		default:
-			break
-		}
-	}
+	pending = append(pending, pid.mbox.close()...)
 
-	for len(pid.stopChan) > 0 {
-		select {
-		case m := <-pid.stopChan:
-			if m != nil {
-				fmt.Printf("%s flushMessages: pid #%d/%s/%s: %#v\n",
-					time.Now().Truncate(time.Microsecond),
-					pid.Id(), prefix, name, m)
-				close(m.replyChan)
-			}
-		default:
-			break
+	for _, m := range pending {
+		switch m := m.(type) {
+		case *genCallReq:
+			pid.log().Warn("pending call dropped",
+				pid.logFields("req", m.data)...)
+			close(m.replyChan)
+		case *stopReq:
+			pid.log().Warn("pending stop dropped",
+				pid.logFields("reason", m.reason)...)
+			close(m.replyChan)
 		}
 	}
 }
@@ -41,7 +41,7 @@ func (gs *GenServerImpl) HandleCall(req Term, from From) Term {
 //
 // Terminate called when process died
 //
-func (gs *GenServerImpl) Terminate(reason string) {
+func (gs *GenServerImpl) Terminate(reason error) {
 }
 
 func (gs *GenServerImpl) setPid(pid *Pid) {
@@ -98,3 +98,79 @@ func (gs *GenServerImpl) NameStr() string {
 		return fmt.Sprintf("%v", name)
 	}
 }
+
+//
+// Env returns the environment the process is spawned in
+//
+func (gs *GenServerImpl) Env() *Act {
+	if gs.self == nil || gs.self.env == nil {
+		return env
+	}
+
+	return gs.self.env
+}
+
+//
+// Register associates the name with pid in the environment of the process
+//
+func (gs *GenServerImpl) Register(name interface{}, pid *Pid) error {
+	return gs.Env().Register(name, pid)
+}
+
+//
+// Whereis returns pid registered with the name in the environment of
+// the process
+//
+func (gs *GenServerImpl) Whereis(name interface{}) *Pid {
+	return gs.Env().Whereis(name)
+}
+
+//
+// Spawn starts a new process in the environment of the process
+//
+func (gs *GenServerImpl) Spawn(
+	server GenServer,
+	args ...interface{}) (*Pid, error) {
+
+	return gs.Env().Spawn(server, args...)
+}
+
+//
+// SpawnOpts starts a new process with options in the environment of
+// the process
+//
+func (gs *GenServerImpl) SpawnOpts(
+	server GenServer,
+	opts *Opts,
+	args ...interface{}) (*Pid, error) {
+
+	return gs.Env().SpawnOpts(server, opts, args...)
+}
+
+//
+// Link links the process with pid
+//
+func (gs *GenServerImpl) Link(pid *Pid) error {
+	return gs.self.Link(pid)
+}
+
+//
+// Unlink removes the link between the process and pid
+//
+func (gs *GenServerImpl) Unlink(pid *Pid) {
+	gs.self.Unlink(pid)
+}
+
+//
+// Monitor starts monitoring of pid by the process
+//
+func (gs *GenServerImpl) Monitor(pid *Pid) MonitorRef {
+	return gs.self.Monitor(pid)
+}
+
+//
+// Demonitor stops monitoring started by Monitor
+//
+func (gs *GenServerImpl) Demonitor(ref MonitorRef) {
+	gs.self.Demonitor(ref)
+}
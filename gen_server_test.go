@@ -1,6 +1,8 @@
 package act
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -51,6 +53,8 @@ var (
 	pid *Pid
 
 	cmdCallError = &reqCallError{}
+
+	errStop = errors.New(cmdStop)
 )
 
 func start(i int) (pid *Pid, err error) {
@@ -121,7 +125,7 @@ func (s *gs) Init(args ...interface{}) Term {
 		switch arg := args[0].(type) {
 		case bool:
 			if arg == true {
-				return &GsInitStop{"simulate init failed"}
+				return &GsInitStop{errors.New("simulate init failed")}
 			}
 		case string:
 			if arg == "bad reply" {
@@ -208,7 +212,7 @@ func (s *gs) HandleCall(req Term, from From) Term {
 	}
 
 	return &GsCallStop{
-		Reason: "unexpected call",
+		Reason: errors.New("unexpected call"),
 		Reply:  fmt.Sprintf("HandleCall, unexpected: %#v\n", req)}
 }
 
@@ -225,7 +229,7 @@ func (s *gs) HandleCast(req Term) Term {
 			s.i = 100
 
 		} else if req == cmdStop {
-			return &GsCastStop{cmdStop}
+			return &GsCastStop{errStop}
 
 		} else if req == cmdCrash {
 			i := 1
@@ -251,7 +255,7 @@ func (s *gs) HandleCast(req Term) Term {
 	return GsCastNoReply
 }
 
-func (s *gs) Terminate(reason string) {
+func (s *gs) Terminate(reason error) {
 	// fmt.Printf("pid # %d: Terminate: %s, need crash: %v\n",
 	// 	s.Self().Id(), reason, s.crash)
 
@@ -585,7 +589,10 @@ func TestBadCastReply3(t *testing.T) {
 		t.Error(err)
 	}
 
-	// send on closed channel
+	// stop request has priority over queued casts, let the cast be handled
+	time.Sleep(100 * time.Millisecond)
+
+	// send to closed mailbox
 	err = pid.Stop()
 	if err == nil {
 		t.Error("server must be stopped")
@@ -801,3 +808,192 @@ func TestCancelCastTimeout(t *testing.T) {
 
 	pid.Stop()
 }
+
+//
+// Call with timeout and context
+//
+func TestCallWithTimeout(t *testing.T) {
+	pid, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	start := time.Now()
+
+	_, err = pid.CallTimeout(cmdCallNoReply, 100*time.Millisecond)
+	if !IsTimeoutError(err) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout error must match context.DeadlineExceeded")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("call timeout took %s", d)
+	}
+
+	r, err := pid.CallTimeout(&reqInc{}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.(*reqInc).i != 1 {
+		t.Errorf("unexpected reply: %#v", r)
+	}
+}
+
+func TestCallContextCancel(t *testing.T) {
+	pid, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err = pid.CallContext(ctx, cmdCallNoReply)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCallContextMailboxFull(t *testing.T) {
+	pid, err := SpawnOpts(new(gs), &Opts{ChanSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// block the process and fill the mailbox
+	go pid.Call(cmdLongCall)
+	time.Sleep(100 * time.Millisecond)
+	pid.Cast(cmdTest)
+
+	_, err = pid.CallTimeout(cmdTest, 100*time.Millisecond)
+	if !IsTimeoutError(err) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
+
+func TestLateReply(t *testing.T) {
+	pid, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	// handler replies after 2 seconds to abandoned caller
+	_, err = pid.CallTimeout(cmdCallNoReply, 100*time.Millisecond)
+	if !IsTimeoutError(err) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+
+	time.Sleep(2 * time.Second)
+
+	if _, err := inc(pid); err != nil {
+		t.Errorf("process must be alive after late reply: %s", err)
+	}
+
+	// reply to closed and already replied channels must not block or panic
+	replyChan := make(chan Term, 1)
+	Reply(replyChan, 1)
+	Reply(replyChan, 2)
+	close(replyChan)
+	Reply(replyChan, 3)
+}
+
+//
+// Context-aware gen server
+//
+type ctxKey string
+
+type gsCtx struct {
+	GenServerImpl
+	casts chan Term
+}
+
+func (s *gsCtx) HandleCallCtx(ctx context.Context, req Term, from From) Term {
+	switch req {
+	case cmdLongCall:
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+		return GsCallReplyOk
+	}
+
+	return &GsCallReply{fmt.Sprintf("%v", ctx.Value(ctxKey("trace")))}
+}
+
+func (s *gsCtx) HandleCastCtx(ctx context.Context, req Term) Term {
+	s.casts <- ctx.Value(ctxKey("trace"))
+
+	return GsCastNoReply
+}
+
+func TestHandlerContext(t *testing.T) {
+	s := &gsCtx{casts: make(chan Term, 1)}
+
+	pid, err := Spawn(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	ctx := context.WithValue(context.Background(), ctxKey("trace"), "id1")
+
+	r, err := pid.CallContext(ctx, cmdTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "id1" {
+		t.Errorf("context value must be passed to HandleCallCtx: %#v", r)
+	}
+
+	r, err = pid.Call(cmdTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != "<nil>" {
+		t.Errorf("call without context must have no value: %#v", r)
+	}
+
+	if err := pid.CastContext(ctx, cmdTest); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case v := <-s.casts:
+		if v != "id1" {
+			t.Errorf("context value must be passed to HandleCastCtx: %#v", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cast not handled")
+	}
+}
+
+func TestHandlerContextCancel(t *testing.T) {
+	pid, err := Spawn(&gsCtx{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	start := time.Now()
+
+	_, err = pid.CallTimeout(cmdLongCall, 100*time.Millisecond)
+	if !IsTimeoutError(err) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+
+	// handler must see the caller gave up and return
+	if _, err := pid.Call(cmdTest); err != nil {
+		t.Error(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("handler did not see cancelled context: %s", d)
+	}
+}
@@ -0,0 +1,380 @@
+package act
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+//
+// GlobalResolver chooses the pid keeping the global name when connected
+// nodes registered the name for different processes. Pids are passed
+// ordered by node name and id, so every node keeps the same pid. The name
+// of the other pid is unregistered, the process keeps running
+//
+type GlobalResolver func(name string, pid1, pid2 *Pid) *Pid
+
+//
+// globalTable stores global names of the environment: names of local
+// processes and names registered by connected nodes
+//
+type globalTable struct {
+	mu       sync.Mutex
+	names    map[string]*Pid
+	resolver GlobalResolver
+}
+
+//
+// globalNames are global names of local processes sent to the connected node
+//
+type globalNames map[string]*Pid
+
+func init() {
+	RegisterType("act.globalNames", globalNames{})
+}
+
+// ---------------------------------------------------------------------------
+//
+// RegisterGlobal associates the name with pid in the default environment
+// and connected nodes
+//
+func RegisterGlobal(name string, pid *Pid) error {
+	return env.RegisterGlobal(name, pid)
+}
+
+//
+// RegisterGlobal associates the name with pid in the environment and all
+// connected nodes. The name is not registered if it is registered on any
+// of the nodes. The name is unregistered when the process exits or
+// the connection to the node of the process is lost
+//
+func (a *Act) RegisterGlobal(name string, pid *Pid) error {
+	if !pid.IsAlive() {
+		return GsNoProcError
+	}
+
+	if err := a.globals.register(name, pid); err != nil {
+		return err
+	}
+
+	var (
+		registered []*Node
+		err        error
+	)
+	for _, n := range a.connectedNodes() {
+		r, rerr := n.request(context.Background(),
+			&frame{Type: frameGlobalRegister, Name: name, Data: pid})
+		if rerr == nil {
+			rerr = decodeError(r.Err)
+		}
+		if rerr != nil && n.IsAlive() {
+			err = fmt.Errorf("node %s: %w", n.Name(), rerr)
+			break
+		}
+		registered = append(registered, n)
+	}
+
+	if err != nil {
+		a.globals.unregister(name, pid)
+		for _, n := range registered {
+			n.send(&frame{Type: frameGlobalUnregister, Name: name, Data: pid})
+		}
+		return err
+	}
+
+	return nil
+}
+
+//
+// UnregisterGlobal removes the global name of the default environment
+//
+func UnregisterGlobal(name string) {
+	env.UnregisterGlobal(name)
+}
+
+//
+// UnregisterGlobal removes the global name in the environment and all
+// connected nodes
+//
+func (a *Act) UnregisterGlobal(name string) {
+	pid := a.WhereisGlobal(name)
+	if pid == nil {
+		return
+	}
+
+	a.globals.unregister(name, pid)
+	a.broadcastUnregister(name, pid)
+}
+
+//
+// WhereisGlobal returns the pid registered with the global name in
+// the default environment
+//
+func WhereisGlobal(name string) *Pid {
+	return env.WhereisGlobal(name)
+}
+
+//
+// WhereisGlobal returns the pid registered with the global name, local or
+// remote, nil if the name is not registered
+//
+func (a *Act) WhereisGlobal(name string) *Pid {
+	a.globals.mu.Lock()
+	pid := a.globals.names[name]
+	a.globals.mu.Unlock()
+
+	if !pid.IsAlive() {
+		return nil
+	}
+
+	return pid
+}
+
+//
+// SetGlobalResolver sets the resolver of global name conflicts of
+// the default environment
+//
+func SetGlobalResolver(resolver GlobalResolver) {
+	env.SetGlobalResolver(resolver)
+}
+
+//
+// SetGlobalResolver sets the resolver of global name conflicts found when
+// nodes connect. By default the first pid keeps the name. All nodes must
+// use the same resolver
+//
+func (a *Act) SetGlobalResolver(resolver GlobalResolver) {
+	a.globals.mu.Lock()
+	a.globals.resolver = resolver
+	a.globals.mu.Unlock()
+}
+
+// ---------------------------------------------------------------------------
+func (a *Act) connectedNodes() []*Node {
+	a.nodes.mu.Lock()
+	defer a.nodes.mu.Unlock()
+
+	nodes := make([]*Node, 0, len(a.nodes.byName))
+	for _, n := range a.nodes.byName {
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
+
+func (a *Act) broadcastUnregister(name string, pid *Pid) {
+	for _, n := range a.connectedNodes() {
+		n.send(&frame{Type: frameGlobalUnregister, Name: name, Data: pid})
+	}
+}
+
+//
+// register adds the name if it is not registered for other alive process
+//
+// Locks are taken in order: table, then pid
+//
+func (t *globalTable) register(name string, pid *Pid) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if old := t.names[name]; old != nil && old != pid && old.IsAlive() {
+		return fmt.Errorf("name '%s' already registered globally", name)
+	}
+
+	if !pid.addGlobal(name) {
+		return GsNoProcError
+	}
+
+	if t.names == nil {
+		t.names = make(map[string]*Pid)
+	}
+	t.names[name] = pid
+
+	return nil
+}
+
+//
+// unregister removes the name if it is registered for the pid
+//
+func (t *globalTable) unregister(name string, pid *Pid) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.names[name] == pid {
+		delete(t.names, name)
+		pid.removeGlobal(name)
+	}
+}
+
+//
+// removeNode removes names of processes of the disconnected node
+//
+func (t *globalTable) removeNode(n *Node) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for name, pid := range t.names {
+		if pid.node == n {
+			delete(t.names, name)
+		}
+	}
+}
+
+//
+// local returns names of local processes
+//
+func (t *globalTable) local() globalNames {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make(globalNames)
+	for name, pid := range t.names {
+		if pid.node == nil {
+			names[name] = pid
+		}
+	}
+
+	return names
+}
+
+//
+// merge adds names of the connected node, conflicts are resolved by
+// the resolver
+//
+func (t *globalTable) merge(names globalNames) {
+	for name, pid := range names {
+		if pid == nil {
+			continue
+		}
+
+		t.mu.Lock()
+		old := t.names[name]
+		resolver := t.resolver
+		t.mu.Unlock()
+
+		winner := pid
+		if old != nil && old != pid && old.IsAlive() {
+			winner = resolveGlobal(resolver, name, old, pid)
+		}
+
+		t.mu.Lock()
+		if t.names[name] == old {
+			if winner != old && old != nil {
+				old.removeGlobal(name)
+			}
+			if t.names == nil {
+				t.names = make(map[string]*Pid)
+			}
+			t.names[name] = winner
+		}
+		t.mu.Unlock()
+	}
+}
+
+//
+// resolveGlobal calls the resolver with pids ordered by node name and id
+//
+func resolveGlobal(resolver GlobalResolver, name string, p1, p2 *Pid) *Pid {
+	if p2.Node() < p1.Node() || (p2.Node() == p1.Node() && p2.Id() < p1.Id()) {
+		p1, p2 = p2, p1
+	}
+
+	if resolver == nil {
+		return p1
+	}
+
+	if winner := resolver(name, p1, p2); winner == p2 {
+		return p2
+	}
+
+	return p1
+}
+
+// ---------------------------------------------------------------------------
+//
+// syncGlobals sends global names of local processes to the connected node
+//
+func (n *Node) syncGlobals() {
+	n.send(&frame{Type: frameGlobalSync, Data: n.env.globals.local()})
+}
+
+func (n *Node) handleGlobal(f *frame) {
+	t := &n.env.globals
+	name, _ := f.Name.(string)
+	pid, _ := f.Data.(*Pid)
+
+	switch f.Type {
+
+	case frameGlobalRegister:
+		if pid == nil {
+			n.reply(f.Ref, nil, GsNoProcError)
+			return
+		}
+
+		t.mu.Lock()
+		old := t.names[name]
+		if old != nil && old != pid && old.IsAlive() {
+			t.mu.Unlock()
+			n.reply(f.Ref, nil,
+				fmt.Errorf("name '%s' already registered globally", name))
+			return
+		}
+		if t.names == nil {
+			t.names = make(map[string]*Pid)
+		}
+		t.names[name] = pid
+		t.mu.Unlock()
+
+		n.reply(f.Ref, nil, nil)
+
+	case frameGlobalUnregister:
+		t.unregister(name, pid)
+
+	case frameGlobalSync:
+		names, _ := f.Data.(globalNames)
+		t.merge(names)
+	}
+}
+
+// ---------------------------------------------------------------------------
+func (pid *Pid) addGlobal(name string) bool {
+	if pid.node != nil {
+		return pid.IsAlive()
+	}
+
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	if pid.exited {
+		return false
+	}
+
+	if pid.globals == nil {
+		pid.globals = make(map[string]struct{})
+	}
+	pid.globals[name] = struct{}{}
+
+	return true
+}
+
+func (pid *Pid) removeGlobal(name string) {
+	pid.mu.Lock()
+	delete(pid.globals, name)
+	pid.mu.Unlock()
+}
+
+//
+// unregisterGlobals removes global names of the exited process in
+// the environment and connected nodes
+//
+func (pid *Pid) unregisterGlobals() {
+	pid.mu.Lock()
+	names := pid.globals
+	pid.globals = nil
+	pid.mu.Unlock()
+
+	for name := range names {
+		pid.env.globals.unregister(name, pid)
+		pid.env.broadcastUnregister(name, pid)
+	}
+}
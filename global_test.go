@@ -0,0 +1,153 @@
+package act
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func spawnIdle(t *testing.T, a *Act) *Pid {
+	pid, err := a.Spawn(&gsOrder{release: make(chan struct{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pid
+}
+
+//
+// waitGlobal waits until the global name is registered for the pid,
+// local or remote one
+//
+func waitGlobal(t *testing.T, a *Act, name string, pid *Pid) {
+	t.Helper()
+
+	same := func(p *Pid) bool {
+		if p == nil || pid == nil {
+			return p == pid
+		}
+		return p.Node() == pid.Node() && p.Id() == pid.Id()
+	}
+
+	for i := 0; i < 100; i++ {
+		if same(a.WhereisGlobal(name)) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("want '%s' registered for #%d at %s, got %v",
+		name, pid.Id(), pid.Node(), a.WhereisGlobal(name))
+}
+
+func TestGlobalRegister(t *testing.T) {
+	a, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	pid := spawnIdle(t, a)
+
+	if err := a.RegisterGlobal("service", pid); err != nil {
+		t.Fatal(err)
+	}
+	if a.WhereisGlobal("service") != pid {
+		t.Error("want local pid")
+	}
+
+	remote := b.WhereisGlobal("service")
+	if remote == nil || remote.Node() != a.NodeName() || remote.Id() != pid.Id() {
+		t.Fatalf("want remote pid #%d, got %v", pid.Id(), remote)
+	}
+	if r, err := remote.Call("order"); err != nil {
+		t.Errorf("call of global name: %v, %v", r, err)
+	}
+
+	// registered on other node
+	other := spawnIdle(t, b)
+	if err := b.RegisterGlobal("service", other); err == nil {
+		t.Error("want already registered error")
+	}
+	if err := a.RegisterGlobal("service", pid); err != nil {
+		t.Errorf("register again: %v", err)
+	}
+
+	// unregister
+	b.UnregisterGlobal("service")
+	waitGlobal(t, a, "service", nil)
+
+	if err := b.RegisterGlobal("service", other); err != nil {
+		t.Fatal(err)
+	}
+	waitGlobal(t, a, "service", other)
+
+	// exit of the process
+	other.Stop()
+	waitGlobal(t, a, "service", nil)
+	if b.WhereisGlobal("service") != nil {
+		t.Error("name of exited process must be unregistered")
+	}
+
+	// disconnect
+	if err := b.RegisterGlobal("other", spawnIdle(t, b)); err != nil {
+		t.Fatal(err)
+	}
+	if a.WhereisGlobal("other") == nil {
+		t.Fatal("want remote pid")
+	}
+	node.Close()
+	waitGlobal(t, a, "other", nil)
+}
+
+func TestGlobalConflict(t *testing.T) {
+	a := NewEnv()
+	b := NewEnv()
+	defer func() {
+		b.Shutdown(contextTimeout(t))
+		a.Shutdown(contextTimeout(t))
+	}()
+
+	var calls atomic.Int32
+	resolver := func(name string, pid1, pid2 *Pid) *Pid {
+		calls.Add(1)
+		return pid2
+	}
+	a.SetGlobalResolver(resolver)
+	b.SetGlobalResolver(resolver)
+
+	if err := a.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// partition: both register the same name
+	pa := spawnIdle(t, a)
+	pb := spawnIdle(t, b)
+	if err := a.RegisterGlobal("leader", pa); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.RegisterGlobal("leader", pb); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RegisterGlobal("only-a", pa); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := b.Connect(a.NodeName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pids are ordered by node name, the resolver chooses the second one
+	want := pb
+	if a.NodeName() > b.NodeName() {
+		want = pa
+	}
+
+	waitGlobal(t, a, "leader", want)
+	waitGlobal(t, b, "leader", want)
+	waitGlobal(t, b, "only-a", pa)
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("want resolver called on both nodes, got %d calls", n)
+	}
+	if node.Name() != a.NodeName() {
+		t.Errorf("want node %s, got %s", a.NodeName(), node.Name())
+	}
+}
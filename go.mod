@@ -0,0 +1,3 @@
+module github.com/tdx/act
+
+go 1.24
@@ -0,0 +1,175 @@
+package act
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+//
+// groups stores process groups of the environment. A process can be
+// a member of many groups, it leaves all of them on exit
+//
+// Locks are taken in order: groups, then pid
+//
+type groups struct {
+	mu      sync.RWMutex
+	members map[string]map[*Pid]struct{}
+}
+
+type groupKey struct {
+	groups *groups
+	name   string
+}
+
+//
+// Join adds the process to the group
+//
+func Join(group string, pid *Pid) error {
+	return env.Join(group, pid)
+}
+
+func (a *Act) Join(group string, pid *Pid) error {
+	if err := a.groups.join(group, pid); err != nil {
+		return err
+	}
+
+	pid.watchRemote()
+
+	return nil
+}
+
+//
+// Leave removes the process from the group
+//
+func Leave(group string, pid *Pid) {
+	env.Leave(group, pid)
+}
+
+func (a *Act) Leave(group string, pid *Pid) {
+	a.groups.leave(group, pid)
+}
+
+//
+// Members returns processes of the group ordered by id
+//
+func Members(group string) []*Pid {
+	return env.Members(group)
+}
+
+func (a *Act) Members(group string) []*Pid {
+	return a.groups.list(group)
+}
+
+//
+// Broadcast casts the message to all members of the group. Errors of casts
+// to members are joined
+//
+func Broadcast(group string, data Term) error {
+	return env.Broadcast(group, data)
+}
+
+func (a *Act) Broadcast(group string, data Term) error {
+	var errs []error
+
+	for _, pid := range a.groups.list(group) {
+		if err := pid.Cast(data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ---------------------------------------------------------------------------
+func (g *groups) join(group string, pid *Pid) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !pid.addGroup(groupKey{g, group}) {
+		return GsNoProcError
+	}
+
+	if g.members == nil {
+		g.members = make(map[string]map[*Pid]struct{})
+	}
+	if g.members[group] == nil {
+		g.members[group] = make(map[*Pid]struct{})
+	}
+	g.members[group][pid] = struct{}{}
+
+	return nil
+}
+
+func (g *groups) leave(group string, pid *Pid) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if pid == nil {
+		return
+	}
+
+	pid.mu.Lock()
+	delete(pid.groups, groupKey{g, group})
+	pid.mu.Unlock()
+
+	g.remove(group, pid)
+}
+
+func (g *groups) remove(group string, pid *Pid) {
+	delete(g.members[group], pid)
+	if len(g.members[group]) == 0 {
+		delete(g.members, group)
+	}
+}
+
+func (g *groups) list(group string) []*Pid {
+	g.mu.RLock()
+	pids := make([]*Pid, 0, len(g.members[group]))
+	for pid := range g.members[group] {
+		pids = append(pids, pid)
+	}
+	g.mu.RUnlock()
+
+	sort.Slice(pids, func(i, j int) bool {
+		return pids[i].Id() < pids[j].Id()
+	})
+
+	return pids
+}
+
+func (pid *Pid) addGroup(key groupKey) bool {
+	if pid == nil {
+		return false
+	}
+
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	if pid.exited {
+		return false
+	}
+
+	if pid.groups == nil {
+		pid.groups = make(map[groupKey]struct{})
+	}
+	pid.groups[key] = struct{}{}
+
+	return true
+}
+
+//
+// leaveGroups removes the exited process from all groups it is member of
+//
+func (pid *Pid) leaveGroups() {
+	pid.mu.Lock()
+	keys := pid.groups
+	pid.groups = nil
+	pid.mu.Unlock()
+
+	for key := range keys {
+		key.groups.mu.Lock()
+		key.groups.remove(key.name, pid)
+		key.groups.mu.Unlock()
+	}
+}
@@ -0,0 +1,82 @@
+package act
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroups(t *testing.T) {
+	env := NewEnv()
+
+	pid1, err := env.startServerOpts(&Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid1.Stop()
+
+	pid2, err := env.startServerOpts(&Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, group := range []string{"g1", "g2"} {
+		if err := env.Join(group, pid1); err != nil {
+			t.Fatal(err)
+		}
+		if err := env.Join(group, pid2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	members := env.Members("g1")
+	if len(members) != 2 || members[0] != pid1 || members[1] != pid2 {
+		t.Fatalf("unexpected members: %v", members)
+	}
+
+	if err := env.Broadcast("g1", cmdTest); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	for _, pid := range members {
+		if r, _ := inc(pid); r != 101 {
+			t.Errorf("pid #%d: broadcast not received", pid.Id())
+		}
+	}
+
+	env.Leave("g1", pid1)
+	if members := env.Members("g1"); len(members) != 1 || members[0] != pid2 {
+		t.Errorf("unexpected members after leave: %v", members)
+	}
+
+	// exited process leaves all groups
+	pid2.Stop()
+
+	if members := env.Members("g1"); len(members) != 0 {
+		t.Errorf("unexpected members after exit: %v", members)
+	}
+	if members := env.Members("g2"); len(members) != 1 || members[0] != pid1 {
+		t.Errorf("unexpected members after exit: %v", members)
+	}
+
+	// default environment has no such groups
+	if members := Members("g2"); len(members) != 0 {
+		t.Errorf("groups of environment must be separated: %v", members)
+	}
+}
+
+func TestJoinStopped(t *testing.T) {
+	pid, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid.Stop()
+
+	if err := Join("g1", pid); !IsNoProcError(err) {
+		t.Errorf("join stopped process must fail with no_proc: %v", err)
+	}
+
+	if err := Broadcast("empty group", cmdTest); err != nil {
+		t.Errorf("broadcast to empty group: %v", err)
+	}
+}
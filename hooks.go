@@ -0,0 +1,80 @@
+package act
+
+import (
+	"fmt"
+	"time"
+)
+
+//
+// Hooks are called on events of processes of the environment, for metrics
+// or tracing. OnSpawn is called in the goroutine of the spawner, other hooks
+// are called in the process goroutine. Hooks must be fast, a panicking hook
+// is logged and ignored
+//
+type Hooks interface {
+	// OnSpawn is called after the process is initialized, before Spawn
+	// returns
+	OnSpawn(pid *Pid)
+	// OnMessageIn is called before the call or cast is handled
+	OnMessageIn(pid *Pid, msg Term)
+	// OnHandled is called after the call or cast is handled with the
+	// handler duration and result
+	OnHandled(pid *Pid, msg Term, duration time.Duration, result Term)
+	// OnTerminate is called when the process exits
+	OnTerminate(pid *Pid, reason error)
+	// OnCrash is called when the process panics
+	OnCrash(report *CrashReport)
+}
+
+//
+// NopHooks implements Hooks doing nothing, embed it to implement only
+// needed hooks
+//
+type NopHooks struct{}
+
+func (NopHooks) OnSpawn(pid *Pid)                                        {}
+func (NopHooks) OnMessageIn(pid *Pid, msg Term)                          {}
+func (NopHooks) OnHandled(pid *Pid, msg Term, d time.Duration, res Term) {}
+func (NopHooks) OnTerminate(pid *Pid, reason error)                      {}
+func (NopHooks) OnCrash(report *CrashReport)                             {}
+
+type hooksBox struct {
+	hooks Hooks
+}
+
+//
+// SetHooks sets hooks of the default environment
+//
+func SetHooks(h Hooks) {
+	env.SetHooks(h)
+}
+
+//
+// SetHooks sets hooks of the environment, nil removes hooks
+//
+func (a *Act) SetHooks(h Hooks) {
+	a.hooks.Store(&hooksBox{h})
+}
+
+// ---------------------------------------------------------------------------
+func (pid *Pid) hooks() Hooks {
+	if box := pid.env.hooks.Load(); box != nil {
+		return box.hooks
+	}
+
+	return nil
+}
+
+//
+// callHook calls the hook, a panic of the hook is logged and ignored
+//
+func (pid *Pid) callHook(hook string, call func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			pid.log().Error("hook panic", pid.logFields(
+				"hook", hook, "panic", fmt.Sprintf("%#v", r))...)
+		}
+	}()
+
+	call()
+}
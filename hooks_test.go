@@ -0,0 +1,168 @@
+package act
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+//
+// records events
+//
+type testHooks struct {
+	NopHooks
+	mu      sync.Mutex
+	events  []string
+	handled []time.Duration
+	crashes []*CrashReport
+	reasons []error
+}
+
+func (h *testHooks) add(event string) {
+	h.mu.Lock()
+	h.events = append(h.events, event)
+	h.mu.Unlock()
+}
+
+func (h *testHooks) OnSpawn(pid *Pid) {
+	h.add("spawn")
+}
+
+func (h *testHooks) OnMessageIn(pid *Pid, msg Term) {
+	h.add("in:" + msg.(string))
+}
+
+func (h *testHooks) OnHandled(
+	pid *Pid,
+	msg Term,
+	d time.Duration,
+	result Term) {
+
+	h.add("handled:" + msg.(string))
+
+	h.mu.Lock()
+	h.handled = append(h.handled, d)
+	h.mu.Unlock()
+}
+
+func (h *testHooks) OnTerminate(pid *Pid, reason error) {
+	h.add("terminate")
+
+	h.mu.Lock()
+	h.reasons = append(h.reasons, reason)
+	h.mu.Unlock()
+}
+
+func (h *testHooks) OnCrash(report *CrashReport) {
+	h.add("crash")
+
+	h.mu.Lock()
+	h.crashes = append(h.crashes, report)
+	h.mu.Unlock()
+}
+
+func TestHooks(t *testing.T) {
+	a := NewEnv()
+	h := new(testHooks)
+	a.SetHooks(h)
+
+	pid, err := a.Spawn(new(gsCrash))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid.Call("ok")
+	pid.Stop()
+
+	want := []string{"spawn", "in:ok", "handled:ok", "terminate"}
+	if !equalOrder(h.events, want) {
+		t.Errorf("want %v, got %v", want, h.events)
+	}
+	if len(h.handled) != 1 || h.handled[0] < 0 {
+		t.Errorf("bad handle duration: %v", h.handled)
+	}
+	if len(h.reasons) != 1 || h.reasons[0] != Shutdown {
+		t.Errorf("want Shutdown reason, got %v", h.reasons)
+	}
+}
+
+func TestHooksCrash(t *testing.T) {
+	a := NewEnv()
+	a.SetLogger(new(testLogger))
+	h := new(testHooks)
+	a.SetHooks(h)
+
+	pid, err := a.Spawn(new(gsCrash))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid.Cast("panic")
+	time.Sleep(50 * time.Millisecond)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	want := []string{"spawn", "in:panic", "crash", "terminate"}
+	if !equalOrder(h.events, want) {
+		t.Errorf("want %v, got %v", want, h.events)
+	}
+	if len(h.crashes) != 1 || h.crashes[0].Message != "panic" {
+		t.Errorf("bad crash report: %v", h.crashes)
+	}
+	if len(h.reasons) != 1 || !IsCrash(h.reasons[0]) {
+		t.Errorf("want crash reason, got %v", h.reasons)
+	}
+}
+
+//
+// panics in hooks
+//
+type panicHooks struct {
+	NopHooks
+}
+
+func (panicHooks) OnSpawn(pid *Pid) {
+	panic("spawn")
+}
+
+func (panicHooks) OnTerminate(pid *Pid, reason error) {
+	panic("terminate")
+}
+
+func TestHooksPanic(t *testing.T) {
+	a := NewEnv()
+	l := new(testLogger)
+	a.SetLogger(l)
+	a.SetHooks(panicHooks{})
+
+	pid, err := a.SpawnOpts(new(gsCrash), &Opts{Name: "hookPanic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pid.Call("ok"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Whereis("hookPanic") != nil {
+		t.Error("process must be unregistered")
+	}
+
+	var hooks []string
+	l.mu.Lock()
+	for _, r := range l.records {
+		if r.level == "error" && r.msg == "hook panic" {
+			hooks = append(hooks, r.fields["hook"].(string))
+		}
+	}
+	l.mu.Unlock()
+
+	if !equalOrder(hooks, []string{"OnSpawn", "OnTerminate"}) {
+		t.Errorf("hook panics must be logged, got %v", hooks)
+	}
+}
@@ -0,0 +1,114 @@
+package act
+
+import (
+	"fmt"
+	"time"
+)
+
+//
+// ProcessStatus is the state of the process loop
+//
+type ProcessStatus int32
+
+const (
+	// StatusIdle - the process waits for messages
+	StatusIdle ProcessStatus = iota
+	// StatusCall - the process handles a call
+	StatusCall
+	// StatusCast - the process handles a cast
+	StatusCast
+)
+
+func (s ProcessStatus) String() string {
+	switch s {
+	case StatusIdle:
+		return "idle"
+	case StatusCall:
+		return "call"
+	case StatusCast:
+		return "cast"
+	}
+
+	return "unknown"
+}
+
+//
+// ProcessInfo describes the live process
+//
+type ProcessInfo struct {
+	Pid          *Pid
+	Id           uint64
+	Prefix       string      // prefix the process was spawned with
+	Name         interface{} // name the process was spawned with
+	MailboxLen   int
+	MailboxCap   int // limit of normal and high messages each, 0 if unbounded
+	Processed    uint64
+	Status       ProcessStatus
+	Started      time.Time
+	LastActivity time.Time // last message received, zero if none
+	TimeoutAt    time.Time // inactivity timeout, zero if not set
+}
+
+//
+// IsAlive checks if the process is running
+//
+func (pid *Pid) IsAlive() bool {
+	if pid == nil {
+		return false
+	}
+
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	return !pid.exited
+}
+
+//
+// Info returns the process information or GsNoProcError if the process
+// exited
+//
+func (pid *Pid) Info() (ProcessInfo, error) {
+	if !pid.IsAlive() {
+		return ProcessInfo{}, GsNoProcError
+	}
+
+	if pid.node != nil {
+		return ProcessInfo{}, fmt.Errorf(
+			"pid #%d is the process of node %s", pid.id, pid.node.name)
+	}
+
+	info := ProcessInfo{
+		Pid:        pid,
+		Id:         pid.id,
+		Prefix:     pid.prefix,
+		Name:       pid.name,
+		MailboxLen: pid.mbox.len(),
+		Processed:  pid.processed.Load(),
+		Status:     ProcessStatus(pid.status.Load()),
+		Started:    pid.started,
+	}
+
+	if pid.mbox.policy != MailboxUnbounded {
+		info.MailboxCap = pid.mbox.size
+	}
+
+	if t := pid.lastActivity.Load(); t != 0 {
+		info.LastActivity = time.Unix(0, t)
+	}
+
+	if t := pid.timeoutAt.Load(); t != 0 {
+		info.TimeoutAt = time.Unix(0, t)
+	}
+
+	return info, nil
+}
+
+// ---------------------------------------------------------------------------
+func (pid *Pid) handling(status ProcessStatus) {
+	pid.status.Store(int32(status))
+}
+
+func (pid *Pid) handled() {
+	pid.status.Store(int32(StatusIdle))
+	pid.processed.Add(1)
+}
@@ -0,0 +1,114 @@
+package act
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// blocks in call until released, sets inactivity timeout on cast
+//
+type gsInfo struct {
+	GenServerImpl
+	release chan struct{}
+}
+
+func (s *gsInfo) HandleCall(req Term, from From) Term {
+	if req == "wait" {
+		<-s.release
+	}
+
+	return GsCallReplyOk
+}
+
+func (s *gsInfo) HandleCast(req Term) Term {
+	if req == "timeout" {
+		return &GsCastNoReplyTimeout{10000}
+	}
+
+	return GsCastNoReply
+}
+
+func TestIsAlive(t *testing.T) {
+	pid, err := Spawn(new(gsInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pid.IsAlive() {
+		t.Error("process must be alive")
+	}
+
+	pid.Stop()
+
+	if pid.IsAlive() {
+		t.Error("process must be stopped")
+	}
+
+	if _, err := pid.Info(); !IsNoProcError(err) {
+		t.Errorf("want GsNoProcError, got %v", err)
+	}
+
+	var nilPid *Pid
+	if nilPid.IsAlive() {
+		t.Error("nil pid must not be alive")
+	}
+}
+
+func TestInfo(t *testing.T) {
+	start := time.Now()
+
+	s := &gsInfo{release: make(chan struct{})}
+	pid, err := SpawnOpts(s, &Opts{Prefix: "info", Name: "test", ChanSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	info, err := pid.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Pid != pid || info.Id != pid.Id() ||
+		info.Prefix != "info" || info.Name != "test" {
+		t.Errorf("bad identity: %+v", info)
+	}
+	if info.Status != StatusIdle || info.Processed != 0 ||
+		!info.LastActivity.IsZero() || !info.TimeoutAt.IsZero() {
+		t.Errorf("bad idle info: %+v", info)
+	}
+	if info.MailboxCap != 10 || info.Started.Before(start) {
+		t.Errorf("bad info: %+v", info)
+	}
+
+	pid.Cast("timeout")
+	time.Sleep(50 * time.Millisecond)
+
+	info, _ = pid.Info()
+	if info.Processed != 1 || info.TimeoutAt.IsZero() ||
+		info.LastActivity.Before(start) {
+		t.Errorf("bad info after cast: %+v", info)
+	}
+
+	go pid.Call("wait")
+	time.Sleep(50 * time.Millisecond)
+	pid.Cast("queued")
+
+	info, _ = pid.Info()
+	if info.Status != StatusCall || info.MailboxLen != 1 ||
+		!info.TimeoutAt.IsZero() {
+		t.Errorf("bad info in call: %+v", info)
+	}
+	if info.Status.String() != "call" {
+		t.Errorf("want status 'call', got %s", info.Status)
+	}
+
+	close(s.release)
+	time.Sleep(50 * time.Millisecond)
+
+	info, _ = pid.Info()
+	if info.Status != StatusIdle || info.Processed != 3 {
+		t.Errorf("bad info after call: %+v", info)
+	}
+}
@@ -0,0 +1,161 @@
+package act
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+//
+// Kind creates the GenServer of the process spawned by SpawnOn
+//
+type Kind func() GenServer
+
+//
+// kindTable stores kinds of processes which can be spawned by other nodes
+//
+type kindTable struct {
+	mu    sync.RWMutex
+	kinds map[string]Kind
+}
+
+//
+// spawnReq is the request of SpawnOn
+//
+type spawnReq struct {
+	Kind     string
+	Prefix   string
+	Name     jsonTerm
+	ChanSize uint32
+	Mailbox  MailboxPolicy
+	TrapExit bool
+	Args     []jsonTerm
+}
+
+func init() {
+	RegisterType("act.spawnReq", new(spawnReq))
+}
+
+// ---------------------------------------------------------------------------
+//
+// RegisterKind registers the kind of processes of the default environment
+//
+func RegisterKind(kind string, factory Kind) {
+	env.RegisterKind(kind, factory)
+}
+
+//
+// RegisterKind registers the kind of processes connected nodes can spawn
+// in the environment with SpawnOn
+//
+func (a *Act) RegisterKind(kind string, factory Kind) {
+	a.kinds.mu.Lock()
+	if a.kinds.kinds == nil {
+		a.kinds.kinds = make(map[string]Kind)
+	}
+	a.kinds.kinds[kind] = factory
+	a.kinds.mu.Unlock()
+}
+
+//
+// SpawnOn spawns the process of the kind on the node connected to
+// the default environment
+//
+func SpawnOn(
+	node string,
+	kind string,
+	opts *Opts,
+	args ...interface{}) (*Pid, error) {
+
+	return env.SpawnOn(node, kind, opts, args...)
+}
+
+//
+// SpawnOn spawns the process of the kind registered on the connected node
+// with RegisterKind. Opts and args are passed to the node, types of args
+// must be registered with RegisterType. Call, Cast and Stop of the returned
+// pid are forwarded to the process
+//
+func (a *Act) SpawnOn(
+	node string,
+	kind string,
+	opts *Opts,
+	args ...interface{}) (*Pid, error) {
+
+	n := a.Node(node)
+	if n == nil {
+		return nil, fmt.Errorf("node %s: %w", node, ErrNoConnection)
+	}
+
+	if opts == nil {
+		opts = &Opts{}
+	}
+
+	req := &spawnReq{
+		Kind:     kind,
+		Prefix:   opts.Prefix,
+		Name:     jsonTerm{opts.Name},
+		ChanSize: opts.ChanSize,
+		Mailbox:  opts.Mailbox,
+		TrapExit: opts.TrapExit,
+	}
+	for _, arg := range args {
+		req.Args = append(req.Args, jsonTerm{arg})
+	}
+
+	r, err := n.request(context.Background(), &frame{Type: frameSpawn, Data: req})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decodeError(r.Err); err != nil {
+		return nil, err
+	}
+
+	pid, ok := r.Data.(*Pid)
+	if !ok || pid == nil {
+		return nil, GsNoProcError
+	}
+
+	return pid, nil
+}
+
+// ---------------------------------------------------------------------------
+func (t *kindTable) get(kind string) Kind {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.kinds[kind]
+}
+
+//
+// handleSpawn spawns the process requested by the node
+//
+func (n *Node) handleSpawn(f *frame) {
+	req, ok := f.Data.(*spawnReq)
+	if !ok {
+		n.reply(f.Ref, nil, fmt.Errorf("bad spawn request %T", f.Data))
+		return
+	}
+
+	factory := n.env.kinds.get(req.Kind)
+	if factory == nil {
+		n.reply(f.Ref, nil, fmt.Errorf("unknown kind '%s'", req.Kind))
+		return
+	}
+
+	args := make([]interface{}, 0, len(req.Args))
+	for _, arg := range req.Args {
+		args = append(args, arg.Term)
+	}
+
+	pid, err := n.env.SpawnOpts(factory(), &Opts{
+		Prefix:   req.Prefix,
+		Name:     req.Name.Term,
+		ChanSize: req.ChanSize,
+		Mailbox:  req.Mailbox,
+		TrapExit: req.TrapExit,
+	}, args...)
+
+	n.reply(f.Ref, pid, err)
+}
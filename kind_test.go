@@ -0,0 +1,72 @@
+package act
+
+import (
+	"errors"
+	"testing"
+)
+
+//
+// session answers calls with the user passed to Init
+//
+type gsSession struct {
+	GenServerImpl
+	user string
+}
+
+func (s *gsSession) Init(args ...interface{}) Term {
+	if len(args) != 1 {
+		return &GsInitStop{errors.New("user required")}
+	}
+	s.user, _ = args[0].(string)
+
+	return GsInitOk
+}
+
+func (s *gsSession) HandleCall(req Term, from From) Term {
+	return &GsCallReply{s.user}
+}
+
+func TestSpawnOn(t *testing.T) {
+	a, b, _, _ := startNodes(t, "127.0.0.1:0")
+	a.SetLogger(new(testLogger))
+
+	a.RegisterKind("session", func() GenServer { return new(gsSession) })
+
+	pid, err := b.SpawnOn(a.NodeName(), "session", &Opts{Name: "s1"}, "user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := a.Whereis("s1")
+	if local == nil {
+		t.Fatal("process must be spawned on the node")
+	}
+	if pid.Node() != a.NodeName() || pid.Id() != local.Id() {
+		t.Errorf("want remote pid #%d, got #%d at %s", local.Id(), pid.Id(), pid.Node())
+	}
+
+	r, err := pid.Call("user")
+	if err != nil || r != "user-1" {
+		t.Errorf("want 'user-1', got %#v, %v", r, err)
+	}
+
+	if err := pid.Stop(); err != nil {
+		t.Error(err)
+	}
+	if local.IsAlive() {
+		t.Error("process must be stopped")
+	}
+
+	if _, err := b.SpawnOn(a.NodeName(), "session", nil); err == nil ||
+		err.Error() != "user required" {
+		t.Errorf("want init error, got %v", err)
+	}
+
+	if _, err := b.SpawnOn(a.NodeName(), "unknown", nil); err == nil {
+		t.Error("want unknown kind error")
+	}
+
+	if _, err := b.SpawnOn("nowhere", "session", nil); !errors.Is(err, ErrNoConnection) {
+		t.Errorf("want ErrNoConnection, got %v", err)
+	}
+}
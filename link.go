@@ -0,0 +1,232 @@
+package act
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+//
+// MonitorRef identifies a monitor created by Monitor
+//
+type MonitorRef uint64
+
+//
+// Down is sent to the monitoring process when the monitored process exits
+//
+type Down struct {
+	Ref    MonitorRef
+	Pid    *Pid
+	Reason error
+}
+
+//
+// Exit is sent to the process trapping exits when a linked process exits
+//
+type Exit struct {
+	Pid    *Pid
+	Reason error
+}
+
+var monitorSerial uint64
+
+//
+// Link creates a bidirectional link between processes. When one of them
+// exits with reason other than Normal the other one is stopped with Killed
+// reason, or receives the Exit message if it traps exits
+//
+func (pid *Pid) Link(to *Pid) error {
+	if pid == nil || to == nil {
+		return GsNoProcError
+	}
+
+	if pid == to {
+		return nil
+	}
+
+	if !pid.addLink(to) {
+		return GsNoProcError
+	}
+
+	if !to.addLink(pid) {
+		pid.removeLink(to)
+		return GsNoProcError
+	}
+
+	pid.watchRemote()
+	to.watchRemote()
+
+	return nil
+}
+
+//
+// Unlink removes the link between processes
+//
+func (pid *Pid) Unlink(to *Pid) {
+	if pid == nil || to == nil {
+		return
+	}
+
+	pid.removeLink(to)
+	to.removeLink(pid)
+}
+
+//
+// Monitor starts monitoring of the target process. When the target exits the
+// Down message is sent to the process. If the target does not exist the Down
+// message with GsNoProcError reason is sent immediately
+//
+func (pid *Pid) Monitor(target *Pid) MonitorRef {
+	ref := MonitorRef(atomic.AddUint64(&monitorSerial, 1))
+
+	if pid == nil {
+		return ref
+	}
+
+	if target == nil || !target.addMonitor(ref, pid) {
+		pid.castSystem(Down{Ref: ref, Pid: target, Reason: GsNoProcError})
+		return ref
+	}
+
+	pid.mu.Lock()
+	if pid.watching == nil {
+		pid.watching = make(map[MonitorRef]*Pid)
+	}
+	pid.watching[ref] = target
+	pid.mu.Unlock()
+
+	target.watchRemote()
+
+	return ref
+}
+
+//
+// Demonitor stops monitoring started by Monitor
+//
+func (pid *Pid) Demonitor(ref MonitorRef) {
+	if pid == nil {
+		return
+	}
+
+	pid.mu.Lock()
+	target, ok := pid.watching[ref]
+	delete(pid.watching, ref)
+	pid.mu.Unlock()
+
+	if ok {
+		target.removeMonitor(ref)
+	}
+}
+
+// ---------------------------------------------------------------------------
+func (pid *Pid) addLink(to *Pid) bool {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	if pid.exited {
+		return false
+	}
+
+	if pid.links == nil {
+		pid.links = make(map[*Pid]struct{})
+	}
+	pid.links[to] = struct{}{}
+
+	return true
+}
+
+func (pid *Pid) removeLink(to *Pid) {
+	pid.mu.Lock()
+	delete(pid.links, to)
+	pid.mu.Unlock()
+}
+
+func (pid *Pid) addMonitor(ref MonitorRef, watcher *Pid) bool {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	if pid.exited {
+		return false
+	}
+
+	if pid.monitors == nil {
+		pid.monitors = make(map[MonitorRef]*Pid)
+	}
+	pid.monitors[ref] = watcher
+
+	return true
+}
+
+func (pid *Pid) removeMonitor(ref MonitorRef) {
+	pid.mu.Lock()
+	delete(pid.monitors, ref)
+	pid.mu.Unlock()
+}
+
+//
+// exit marks process as exited and notifies linked and monitoring processes
+//
+func (pid *Pid) exit(reason error) {
+	pid.mu.Lock()
+	pid.exited = true
+	links := pid.links
+	monitors := pid.monitors
+	watching := pid.watching
+	nodes := pid.nodes
+	pid.links = nil
+	pid.monitors = nil
+	pid.watching = nil
+	pid.nodes = nil
+	pid.mu.Unlock()
+
+	for n := range nodes {
+		n.send(&frame{Type: frameExited, To: pid.id, Err: encodeError(reason)})
+	}
+
+	for ref, target := range watching {
+		target.removeMonitor(ref)
+	}
+
+	for ref, watcher := range monitors {
+		watcher.mu.Lock()
+		delete(watcher.watching, ref)
+		watcher.mu.Unlock()
+
+		watcher.castSystem(Down{Ref: ref, Pid: pid, Reason: reason})
+	}
+
+	for peer := range links {
+		peer.removeLink(pid)
+		peer.exitSignal(pid, reason)
+	}
+}
+
+func (pid *Pid) exitSignal(from *Pid, reason error) {
+	pid.mu.Lock()
+	exited := pid.exited
+	trapExit := pid.trapExit
+	pid.mu.Unlock()
+
+	if exited {
+		return
+	}
+
+	if pid.node != nil {
+		pid.node.send(&frame{Type: frameExitSignal,
+			To: pid.id, Data: from, Err: encodeError(reason)})
+		return
+	}
+
+	if trapExit {
+		pid.castSystem(Exit{Pid: from, Reason: reason})
+		return
+	}
+
+	if errors.Is(reason, Normal) {
+		return
+	}
+
+	// stop asynchronously, the peer may be waiting for this process
+	go pid.StopReason(
+		fmt.Errorf("%w: linked pid #%d exited: %w", Killed, from.Id(), reason))
+}
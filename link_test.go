@@ -0,0 +1,244 @@
+package act
+
+import (
+	"testing"
+	"time"
+)
+
+//
+// gsWatch forwards Down and Exit messages to the channel
+//
+type gsWatch struct {
+	GenServerImpl
+	events chan Term
+}
+
+func (s *gsWatch) HandleCast(req Term) Term {
+	switch req := req.(type) {
+	case Down, Exit, NodeUp, NodeDown:
+		s.events <- req
+	case string:
+		if req == cmdStop {
+			return &GsCastStop{errStop}
+		}
+	}
+
+	return GsCastNoReply
+}
+
+func startWatch(t *testing.T, opts *Opts) (*Pid, chan Term) {
+	s := &gsWatch{events: make(chan Term, 10)}
+
+	pid, err := SpawnOpts(s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pid, s.events
+}
+
+func waitEvent(t *testing.T, events chan Term) Term {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+
+	return nil
+}
+
+func TestMonitor(t *testing.T) {
+	watcher, events := startWatch(t, &Opts{})
+	defer watcher.Stop()
+
+	target, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := watcher.Monitor(target)
+
+	if err := target.Cast(cmdStop); err != nil {
+		t.Fatal(err)
+	}
+
+	switch e := waitEvent(t, events).(type) {
+	case Down:
+		if e.Ref != ref {
+			t.Errorf("wrong ref: want %d - got %d", ref, e.Ref)
+		}
+		if e.Pid != target {
+			t.Errorf("wrong pid: want #%d - got #%d", target.Id(), e.Pid.Id())
+		}
+		if e.Reason != errStop {
+			t.Errorf("wrong reason: want %s - got %s", errStop, e.Reason)
+		}
+	default:
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
+
+func TestMonitorCrash(t *testing.T) {
+	watcher, events := startWatch(t, &Opts{})
+	defer watcher.Stop()
+
+	target, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher.Monitor(target)
+	target.Call(cmdCrash)
+
+	switch e := waitEvent(t, events).(type) {
+	case Down:
+		if !IsCrash(e.Reason) {
+			t.Errorf("crash reason expected, got %s", e.Reason)
+		}
+	default:
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
+
+func TestMonitorNoProc(t *testing.T) {
+	watcher, events := startWatch(t, &Opts{})
+	defer watcher.Stop()
+
+	target, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.Stop()
+
+	ref := watcher.Monitor(target)
+
+	switch e := waitEvent(t, events).(type) {
+	case Down:
+		if e.Ref != ref || !IsNoProcError(e.Reason) {
+			t.Errorf("unexpected down: %#v", e)
+		}
+	default:
+		t.Fatalf("unexpected event: %#v", e)
+	}
+}
+
+func TestDemonitor(t *testing.T) {
+	watcher, events := startWatch(t, &Opts{})
+	defer watcher.Stop()
+
+	target, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref := watcher.Monitor(target)
+	watcher.Demonitor(ref)
+	target.Stop()
+
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event after demonitor: %#v", e)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestLink(t *testing.T) {
+	pid1, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid2, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher, events := startWatch(t, &Opts{})
+	defer watcher.Stop()
+	watcher.Monitor(pid2)
+
+	if err := pid1.Link(pid2); err != nil {
+		t.Fatal(err)
+	}
+
+	pid1.Call(cmdCrash)
+
+	// linked process must be stopped
+	waitEvent(t, events)
+
+	if _, err := pid2.Call(cmdTest); err == nil {
+		t.Error("linked process must be stopped")
+	}
+}
+
+func TestLinkNormalExit(t *testing.T) {
+	pid1, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid2, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid2.Stop()
+
+	if err := pid1.Link(pid2); err != nil {
+		t.Fatal(err)
+	}
+
+	pid1.StopReason(Normal)
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := inc(pid2); err != nil {
+		t.Errorf("linked process must be alive: %s", err)
+	}
+}
+
+func TestLinkTrapExit(t *testing.T) {
+	watcher, events := startWatch(t, &Opts{TrapExit: true})
+	defer watcher.Stop()
+
+	target, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := watcher.Link(target); err != nil {
+		t.Fatal(err)
+	}
+
+	target.Stop()
+
+	switch e := waitEvent(t, events).(type) {
+	case Exit:
+		if e.Pid != target {
+			t.Errorf("wrong pid: want #%d - got #%d", target.Id(), e.Pid.Id())
+		}
+	default:
+		t.Fatalf("unexpected event: %#v", e)
+	}
+
+	if _, err := watcher.Call(cmdTest); err != nil {
+		t.Errorf("process trapping exits must be alive: %s", err)
+	}
+}
+
+func TestLinkNoProc(t *testing.T) {
+	pid1, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid1.Stop()
+
+	pid2, err := runServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid2.Stop()
+
+	if err := pid1.Link(pid2); !IsNoProcError(err) {
+		t.Errorf("link to stopped process must fail with no_proc: %v", err)
+	}
+}
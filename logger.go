@@ -0,0 +1,98 @@
+package act
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+//
+// Logger logs events of processes with key-value pairs of fields.
+// *slog.Logger implements it
+//
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+//
+// levelEnabler is implemented by loggers able to skip disabled levels,
+// *slog.Logger for example
+//
+type levelEnabler interface {
+	Enabled(ctx context.Context, level slog.Level) bool
+}
+
+//
+// loggerBox keeps Logger in atomic.Pointer
+//
+type loggerBox struct {
+	Logger
+}
+
+//
+// defaultLogger writes warnings and errors to stderr
+//
+var defaultLogger = slog.New(slog.NewTextHandler(
+	os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+//
+// SetLogger sets the logger of the default environment
+//
+func SetLogger(l Logger) {
+	env.SetLogger(l)
+}
+
+//
+// SetLogger sets the logger of the environment. Events are logged with
+// levels: spawn and messages - Debug, terminate - Info, abnormal terminate
+// and dropped messages - Warn, crash - Error. nil restores the default
+// logger writing warnings and errors to stderr
+//
+func (a *Act) SetLogger(l Logger) {
+	if l == nil {
+		l = defaultLogger
+	}
+
+	a.logger.Store(&loggerBox{l})
+}
+
+func (a *Act) log() Logger {
+	if box := a.logger.Load(); box != nil {
+		return box.Logger
+	}
+
+	return defaultLogger
+}
+
+// ---------------------------------------------------------------------------
+func (pid *Pid) log() Logger {
+	return pid.env.log()
+}
+
+//
+// logDebug skips building fields if debug level is disabled
+//
+func (pid *Pid) logDebug(msg string, args ...interface{}) {
+	l := pid.log()
+
+	if e, ok := l.(levelEnabler); ok &&
+		!e.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	l.Debug(msg, pid.logFields(args...)...)
+}
+
+//
+// logFields returns pid, prefix and name fields followed by args
+//
+func (pid *Pid) logFields(args ...interface{}) []interface{} {
+	fields := make([]interface{}, 0, 6+len(args))
+	fields = append(fields,
+		"pid", pid.Id(), "prefix", pid.prefix, "name", pid.name)
+
+	return append(fields, args...)
+}
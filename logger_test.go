@@ -0,0 +1,135 @@
+package act
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//
+// collects logged records
+//
+type logRecord struct {
+	level  string
+	msg    string
+	fields map[interface{}]interface{}
+}
+
+type testLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *testLogger) add(level, msg string, args []interface{}) {
+	fields := make(map[interface{}]interface{})
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[args[i]] = args[i+1]
+	}
+
+	l.mu.Lock()
+	l.records = append(l.records, logRecord{level, msg, fields})
+	l.mu.Unlock()
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) {
+	l.add("debug", msg, args)
+}
+
+func (l *testLogger) Info(msg string, args ...interface{}) {
+	l.add("info", msg, args)
+}
+
+func (l *testLogger) Warn(msg string, args ...interface{}) {
+	l.add("warn", msg, args)
+}
+
+func (l *testLogger) Error(msg string, args ...interface{}) {
+	l.add("error", msg, args)
+}
+
+func (l *testLogger) find(level, msg string) *logRecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := range l.records {
+		if l.records[i].level == level && l.records[i].msg == msg {
+			return &l.records[i]
+		}
+	}
+
+	return nil
+}
+
+type gsPanic struct {
+	GenServerImpl
+}
+
+func (s *gsPanic) HandleCall(req Term, from From) Term {
+	panic("boom")
+}
+
+func TestLogger(t *testing.T) {
+	a := NewEnv()
+	l := new(testLogger)
+	a.SetLogger(l)
+
+	pid, err := a.SpawnOpts(new(gsPanic), &Opts{Prefix: "log", Name: "panic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pid.Call("req"); !IsCrash(err) {
+		t.Fatalf("want crash, got %v", err)
+	}
+
+	r := l.find("debug", "spawn")
+	if r == nil {
+		t.Fatal("spawn must be logged")
+	}
+	if r.fields["pid"] != pid.Id() || r.fields["prefix"] != "log" ||
+		r.fields["name"] != "panic" {
+		t.Errorf("bad fields: %#v", r.fields)
+	}
+
+	if r := l.find("debug", "call"); r == nil || r.fields["req"] != "req" {
+		t.Errorf("call must be logged, got %#v", r)
+	}
+
+	r = l.find("error", "crash")
+	if r == nil {
+		t.Fatal("crash must be logged")
+	}
+	if !strings.Contains(r.fields["panic"].(string), "boom") {
+		t.Errorf("bad panic field: %#v", r.fields["panic"])
+	}
+
+	if l.find("warn", "terminate") == nil {
+		t.Error("abnormal terminate must be logged as warning")
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	a := NewEnv()
+
+	var buf bytes.Buffer
+	a.SetLogger(slog.New(slog.NewTextHandler(
+		&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	pid, err := a.SpawnOpts(new(gsInfo), &Opts{Name: "slog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid.Cast("msg")
+	pid.Stop()
+
+	out := buf.String()
+	if strings.Contains(out, "level=DEBUG") {
+		t.Errorf("debug must be disabled: %s", out)
+	}
+	if !strings.Contains(out, "msg=terminate") ||
+		!strings.Contains(out, "name=slog") {
+		t.Errorf("terminate must be logged: %s", out)
+	}
+}
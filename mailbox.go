@@ -0,0 +1,328 @@
+package act
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+//
+// Priority of the message in the process mailbox
+//
+type Priority int
+
+const (
+	// PriorityNormal is the priority of regular calls and casts
+	PriorityNormal Priority = iota
+	// PriorityHigh messages are handled before normal ones, for example
+	// health checks
+	PriorityHigh
+	// prioritySystem is used for stop requests, timeouts, Down and Exit
+	// messages
+	prioritySystem
+
+	lanes = 3
+)
+
+//
+// MailboxPolicy defines what happens when the mailbox of the process is full
+//
+type MailboxPolicy int
+
+const (
+	// MailboxBlock blocks the sender until there is room in the mailbox
+	MailboxBlock MailboxPolicy = iota
+	// MailboxUnbounded never limits the mailbox, ChanSize is ignored
+	MailboxUnbounded
+	// MailboxDropNewest drops the message being sent. Calls, TryCast and
+	// CastTimeout get ErrMailboxFull
+	MailboxDropNewest
+	// MailboxDropOldest drops the oldest message in the mailbox. Dropped
+	// calls get ErrMailboxFull
+	MailboxDropOldest
+	// MailboxFailFast returns ErrMailboxFull to the sender
+	MailboxFailFast
+)
+
+//
+// ErrMailboxFull is returned when the message does not fit in the mailbox
+//
+var ErrMailboxFull = errors.New("mailbox full")
+
+//
+// errDropped is returned by push for the cast dropped by MailboxDropNewest,
+// Cast ignores it
+//
+var errDropped = errors.New("message dropped")
+
+//
+// errMailboxWait is sent back for the remote cast to the full mailbox with
+// MailboxBlock policy, the sender waits for room
+//
+var errMailboxWait = errors.New("mailbox full, wait for room")
+
+type priorityKey struct{}
+
+//
+// WithPriority returns the context to make CallContext and CastContext
+// with the given priority
+//
+func WithPriority(ctx context.Context, prio Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, prio)
+}
+
+func priorityOf(ctx context.Context) Priority {
+	prio, _ := ctx.Value(priorityKey{}).(Priority)
+
+	return userPriority(prio)
+}
+
+//
+// userPriority limits the priority to priorities of Cast and Call,
+// the system lane is used by the runtime only
+//
+func userPriority(prio Priority) Priority {
+	if prio == PriorityHigh {
+		return prio
+	}
+
+	return PriorityNormal
+}
+
+//
+// mailbox is the queue of incoming messages of the process. Messages are
+// received from the lane with the highest priority first. The normal and
+// high lanes are limited by the size each, the policy defines what happens
+// when the lane is full. The system lane is not limited
+//
+type mailbox struct {
+	mu     sync.Mutex
+	lanes  [lanes][]interface{}
+	size   int
+	policy MailboxPolicy
+	closed bool
+	notify chan struct{} // message is available
+	space  chan struct{} // closed when normal or high lane gets room
+}
+
+//
+// newMailbox returns the mailbox with normal and high lanes of size messages.
+// The lane of zero size holds one message
+//
+func newMailbox(size int, policy MailboxPolicy) *mailbox {
+	if size < 1 {
+		size = 1
+	}
+
+	return &mailbox{
+		size:   size,
+		policy: policy,
+		notify: make(chan struct{}, 1),
+		space:  make(chan struct{}),
+	}
+}
+
+//
+// push puts the message to the lane. If the normal lane is full, the
+// message is handled by the mailbox policy, blocked senders wait for room
+// until ctx is done
+//
+func (mb *mailbox) push(
+	ctx context.Context,
+	prio Priority,
+	m interface{}) error {
+
+	for {
+		space, err := mb.put(prio, m)
+		if space == nil {
+			return err
+		}
+
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+//
+// tryPush puts the message to the lane like push, but returns
+// ErrMailboxFull instead of waiting for room
+//
+func (mb *mailbox) tryPush(prio Priority, m interface{}) error {
+	space, err := mb.put(prio, m)
+	if space != nil || err == errDropped {
+		return ErrMailboxFull
+	}
+
+	return err
+}
+
+//
+// put puts the message to the lane or returns the channel to wait for room
+//
+func (mb *mailbox) put(
+	prio Priority,
+	m interface{}) (space chan struct{}, err error) {
+
+	mb.mu.Lock()
+
+	if mb.closed {
+		mb.mu.Unlock()
+		return nil, GsNoProcError
+	}
+
+	if prio == prioritySystem ||
+		mb.policy == MailboxUnbounded ||
+		len(mb.lanes[prio]) < mb.size {
+
+		mb.lanes[prio] = append(mb.lanes[prio], m)
+		mb.mu.Unlock()
+		mb.signal()
+		return nil, nil
+	}
+
+	switch mb.policy {
+
+	case MailboxDropNewest:
+		mb.mu.Unlock()
+		if _, ok := m.(*genCallReq); ok {
+			return nil, ErrMailboxFull
+		}
+		return nil, errDropped
+
+	case MailboxDropOldest:
+		oldest := mb.lanes[prio][0]
+		mb.lanes[prio][0] = nil
+		mb.lanes[prio] = append(mb.lanes[prio][1:], m)
+		mb.mu.Unlock()
+		mb.signal()
+		if call, ok := oldest.(*genCallReq); ok {
+			Reply(call.replyChan, ErrMailboxFull)
+		}
+		return nil, nil
+
+	case MailboxFailFast:
+		mb.mu.Unlock()
+		return nil, ErrMailboxFull
+	}
+
+	space = mb.space
+	mb.mu.Unlock()
+
+	return space, nil
+}
+
+//
+// closedMailbox returns the mailbox of not local process
+//
+func closedMailbox() *mailbox {
+	mb := newMailbox(0, MailboxBlock)
+	mb.close()
+
+	return mb
+}
+
+func (mb *mailbox) signal() {
+	select {
+	case mb.notify <- struct{}{}:
+	default:
+	}
+}
+
+//
+// pop waits for the message with the highest priority. Returns false if
+// the mailbox is closed
+//
+func (mb *mailbox) pop() (interface{}, bool) {
+	for {
+		if m, ok := mb.tryPop(PriorityNormal); ok {
+			return m, true
+		}
+
+		mb.mu.Lock()
+		closed := mb.closed
+		mb.mu.Unlock()
+
+		if closed {
+			return nil, false
+		}
+
+		<-mb.notify
+	}
+}
+
+//
+// tryPop returns the message with priority not lower than prio if any
+//
+func (mb *mailbox) tryPop(prio Priority) (interface{}, bool) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	for lane := prioritySystem; lane >= prio; lane-- {
+		if len(mb.lanes[lane]) == 0 {
+			continue
+		}
+
+		m := mb.lanes[lane][0]
+		mb.lanes[lane][0] = nil
+		mb.lanes[lane] = mb.lanes[lane][1:]
+
+		if lane != prioritySystem && len(mb.lanes[lane]) == mb.size-1 {
+			close(mb.space)
+			mb.space = make(chan struct{})
+		}
+
+		return m, true
+	}
+
+	return nil, false
+}
+
+func (mb *mailbox) len() int {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	n := 0
+	for _, lane := range mb.lanes {
+		n += len(lane)
+	}
+
+	return n
+}
+
+//
+// close closes the mailbox and returns messages left in it
+//
+func (mb *mailbox) close() []interface{} {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if mb.closed {
+		return nil
+	}
+	mb.closed = true
+
+	var left []interface{}
+	for lane := prioritySystem; lane >= PriorityNormal; lane-- {
+		left = append(left, mb.lanes[lane]...)
+		mb.lanes[lane] = nil
+	}
+
+	close(mb.space)
+	mb.signal()
+
+	return left
+}
+
+//
+// MailboxLen returns the number of messages queued in the process mailbox
+//
+func (pid *Pid) MailboxLen() int {
+	if pid == nil {
+		return 0
+	}
+
+	return pid.mbox.len()
+}
@@ -0,0 +1,388 @@
+package act
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+//
+// records order of handled messages, blocks on "wait" until released
+//
+type gsOrder struct {
+	GenServerImpl
+	release chan struct{}
+	open    bool
+	order   []string
+}
+
+func (s *gsOrder) HandleCall(req Term, from From) Term {
+	switch req {
+	case "order":
+		order := make([]string, len(s.order))
+		copy(order, s.order)
+		return &GsCallReply{order}
+	case "closed":
+		if !s.open {
+			return GsCallDefer
+		}
+	}
+
+	s.order = append(s.order, req.(string))
+
+	return &GsCallReply{req}
+}
+
+func (s *gsOrder) HandleCast(req Term) Term {
+	switch req {
+	case "wait":
+		<-s.release
+		return GsCastNoReply
+	case "open":
+		s.open = true
+	case "closed":
+		if !s.open {
+			return GsCastDefer
+		}
+	}
+
+	s.order = append(s.order, req.(string))
+
+	return GsCastNoReply
+}
+
+func startOrder(t *testing.T, opts *Opts) (*Pid, *gsOrder) {
+	s := &gsOrder{release: make(chan struct{})}
+
+	pid, err := SpawnOpts(s, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pid, s
+}
+
+func orderOf(t *testing.T, pid *Pid) []string {
+	r, err := pid.Call("order")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r.([]string)
+}
+
+func equalOrder(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestPriorityCall(t *testing.T) {
+	pid, s := startOrder(t, &Opts{})
+	defer pid.Stop()
+
+	pid.Cast("wait")
+	for i := 0; i < 10; i++ {
+		pid.Cast("normal")
+	}
+
+	high := make(chan error, 1)
+	go func() {
+		ctx := WithPriority(context.Background(), PriorityHigh)
+		_, err := pid.CallContext(ctx, "high")
+		high <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(s.release)
+
+	if err := <-high; err != nil {
+		t.Fatal(err)
+	}
+
+	order := orderOf(t, pid)
+	if len(order) != 11 || order[0] != "high" {
+		t.Errorf("high priority call must be handled first, got %v", order)
+	}
+}
+
+func TestPriorityLimit(t *testing.T) {
+	pid, s := startFull(t, MailboxFailFast)
+	defer pid.Stop()
+
+	high := WithPriority(context.Background(), PriorityHigh)
+
+	for _, m := range []string{"h1", "h2"} {
+		if err := pid.CastContext(high, m); err != nil {
+			t.Fatalf("high lane must have room, got %v", err)
+		}
+	}
+
+	if err := pid.CastContext(high, "h3"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("want ErrMailboxFull, got %v", err)
+	}
+
+	close(s.release)
+	time.Sleep(50 * time.Millisecond)
+
+	want := []string{"h1", "h2", "a", "b"}
+	if order := orderOf(t, pid); !equalOrder(order, want) {
+		t.Errorf("want %v, got %v", want, order)
+	}
+}
+
+func TestPriorityLimitBlock(t *testing.T) {
+	pid, s := startFull(t, MailboxBlock)
+	defer pid.Stop()
+
+	high := WithPriority(context.Background(), PriorityHigh)
+	pid.CastContext(high, "h1")
+	pid.CastContext(high, "h2")
+
+	ctx, cancel := context.WithTimeout(high, 50*time.Millisecond)
+	defer cancel()
+
+	if err := pid.CastContext(ctx, "h3"); err != context.DeadlineExceeded {
+		t.Errorf("full high lane must block, got %v", err)
+	}
+
+	close(s.release)
+}
+
+func TestPriorityStop(t *testing.T) {
+	pid, s := startOrder(t, &Opts{})
+
+	pid.Cast("wait")
+	for i := 0; i < 50; i++ {
+		pid.Cast("normal")
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- pid.Stop()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(s.release)
+
+	if err := <-stopped; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.order) != 0 {
+		t.Errorf("stop must be handled before casts, handled %d", len(s.order))
+	}
+}
+
+func TestDeferMessages(t *testing.T) {
+	pid, _ := startOrder(t, &Opts{})
+	defer pid.Stop()
+
+	pid.Cast("closed")
+	pid.Cast("a")
+
+	reply := make(chan Term, 1)
+	go func() {
+		r, _ := pid.Call("closed")
+		reply <- r
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case r := <-reply:
+		t.Fatalf("deferred call must wait, got %#v", r)
+	default:
+	}
+
+	pid.Cast("open")
+
+	if r := <-reply; r != "closed" {
+		t.Errorf("want reply 'closed', got %#v", r)
+	}
+
+	want := []string{"a", "open", "closed", "closed"}
+	if order := orderOf(t, pid); !equalOrder(order, want) {
+		t.Errorf("want %v, got %v", want, order)
+	}
+}
+
+func TestDeferOnStop(t *testing.T) {
+	pid, _ := startOrder(t, &Opts{})
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := pid.Call("closed")
+		errc <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	pid.Stop()
+
+	if err := <-errc; !IsNoProcError(err) {
+		t.Errorf("want GsNoProcError for deferred call, got %v", err)
+	}
+}
+
+//
+// starts the process with blocked handler and fills the mailbox
+//
+func startFull(t *testing.T, policy MailboxPolicy) (*Pid, *gsOrder) {
+	pid, s := startOrder(t, &Opts{ChanSize: 2, Mailbox: policy})
+
+	pid.Cast("wait")
+	time.Sleep(50 * time.Millisecond)
+
+	for _, m := range []string{"a", "b"} {
+		if err := pid.Cast(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return pid, s
+}
+
+func TestMailboxUnbounded(t *testing.T) {
+	pid, s := startOrder(t, &Opts{ChanSize: 1, Mailbox: MailboxUnbounded})
+	defer pid.Stop()
+
+	pid.Cast("wait")
+	time.Sleep(50 * time.Millisecond)
+
+	for i := 0; i < 100; i++ {
+		if err := pid.Cast("normal"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := pid.MailboxLen(); n != 100 {
+		t.Errorf("want 100 queued messages, got %d", n)
+	}
+
+	close(s.release)
+
+	if order := orderOf(t, pid); len(order) != 100 {
+		t.Errorf("want 100 handled messages, got %d", len(order))
+	}
+}
+
+func TestMailboxFailFast(t *testing.T) {
+	pid, s := startFull(t, MailboxFailFast)
+	defer pid.Stop()
+
+	if err := pid.Cast("c"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("want ErrMailboxFull, got %v", err)
+	}
+
+	if _, err := pid.Call("c"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("want ErrMailboxFull, got %v", err)
+	}
+
+	if n := pid.MailboxLen(); n != 2 {
+		t.Errorf("want 2 queued messages, got %d", n)
+	}
+
+	close(s.release)
+}
+
+func TestMailboxDropNewest(t *testing.T) {
+	pid, s := startFull(t, MailboxDropNewest)
+	defer pid.Stop()
+
+	if err := pid.Cast("c"); err != nil {
+		t.Errorf("cast must be dropped silently, got %v", err)
+	}
+
+	if _, err := pid.Call("c"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("want ErrMailboxFull, got %v", err)
+	}
+
+	if err := pid.TryCast("c"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("TryCast: want ErrMailboxFull, got %v", err)
+	}
+
+	if err := pid.CastTimeout("c", time.Second); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("CastTimeout: want ErrMailboxFull, got %v", err)
+	}
+
+	close(s.release)
+	time.Sleep(50 * time.Millisecond)
+
+	want := []string{"a", "b"}
+	if order := orderOf(t, pid); !equalOrder(order, want) {
+		t.Errorf("want %v, got %v", want, order)
+	}
+}
+
+func TestMailboxDropOldest(t *testing.T) {
+	pid, s := startFull(t, MailboxDropOldest)
+	defer pid.Stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := pid.Call("c")
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the call dropped "a", then "b" and the call are dropped
+	pid.Cast("d")
+	pid.Cast("e")
+
+	if err := <-errc; !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("want ErrMailboxFull for dropped call, got %v", err)
+	}
+
+	close(s.release)
+	time.Sleep(50 * time.Millisecond)
+
+	want := []string{"d", "e"}
+	if order := orderOf(t, pid); !equalOrder(order, want) {
+		t.Errorf("want %v, got %v", want, order)
+	}
+}
+
+func TestTryCast(t *testing.T) {
+	pid, s := startFull(t, MailboxBlock)
+	defer pid.Stop()
+
+	if err := pid.TryCast("c"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("want ErrMailboxFull, got %v", err)
+	}
+
+	start := time.Now()
+	if err := pid.CastTimeout("c", 50*time.Millisecond); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("want ErrMailboxFull, got %v", err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("CastTimeout must wait for room, waited %s", d)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(s.release)
+	}()
+
+	if err := pid.CastTimeout("c", time.Second); err != nil {
+		t.Errorf("cast must wait for room, got %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if err := pid.TryCast("d"); err != nil {
+		t.Error(err)
+	}
+
+	want := []string{"a", "b", "c", "d"}
+	if order := orderOf(t, pid); !equalOrder(order, want) {
+		t.Errorf("want %v, got %v", want, order)
+	}
+}
@@ -0,0 +1,942 @@
+package act
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//
+// ErrNoConnection is the exit reason of remote processes when
+// the connection to their node is lost
+//
+var ErrNoConnection = errors.New("no connection")
+
+//
+// errConnectSelf is returned by Connect to the address of the environment
+//
+var errConnectSelf = errors.New("connected to itself")
+
+//
+// handshakeTimeout limits the exchange of node names on connect
+//
+const handshakeTimeout = 5 * time.Second
+
+//
+// writeTimeout limits writes of frames outside of the cluster, the cluster
+// limits writes with the heartbeat timeout
+//
+const writeTimeout = 5 * time.Second
+
+//
+// castRetryInterval is the interval of repeats of the remote cast to
+// the full mailbox with MailboxBlock policy
+//
+const castRetryInterval = 10 * time.Millisecond
+
+//
+// Node is the connection to another environment. Pids of processes of
+// the node route Call, Cast and Stop over the connection. When the
+// connection is lost, calls return GsNoProcError and processes monitoring
+// remote pids receive Down with ErrNoConnection reason
+//
+// Messages crossing the connection are encoded with the codec of
+// the connecting environment, their types must be registered with
+// RegisterType. Casts to remote pids wait until the node queues
+// the message, the mailbox policy and errors apply as for local pids
+//
+// Writes of frames are limited by the deadline of the context and by
+// the heartbeat timeout of the cluster, 5 seconds outside of the cluster.
+// The node not reading frames in time is disconnected
+//
+type Node struct {
+	env   *Act
+	name  string
+	conn  net.Conn
+	codec Codec
+
+	ctx    context.Context // cancelled when the connection is closed
+	cancel context.CancelFunc
+
+	wmu     sync.Mutex  // writes of frames
+	beating atomic.Bool // heartbeat in progress
+
+	mu       sync.Mutex
+	closed   bool
+	serial   uint64
+	requests map[uint64]chan *frame
+	pids     map[uint64]*Pid // remote pids by id
+}
+
+//
+// nodeTable stores listeners and connected nodes of the environment
+//
+type nodeTable struct {
+	mu        sync.Mutex
+	name      string
+	closed    bool
+	listeners []net.Listener
+	byName    map[string]*Node
+	monitors  map[string]map[*Pid]struct{} // processes monitoring nodes
+	cluster   *cluster
+}
+
+// ---------------------------------------------------------------------------
+//
+// NodeName returns the name of the default environment node
+//
+func NodeName() string {
+	return env.NodeName()
+}
+
+//
+// NodeName returns the name of the environment node: the name set by
+// SetNodeName, the address of the first listener, or the generated name if
+// the environment communicated with other nodes before Listen. Listeners
+// on the unspecified address are named by the host name
+//
+func (a *Act) NodeName() string {
+	a.nodes.mu.Lock()
+	defer a.nodes.mu.Unlock()
+
+	return a.nodes.localName()
+}
+
+//
+// SetNodeName sets the name of the default environment node
+//
+func SetNodeName(name string) error {
+	return env.SetNodeName(name)
+}
+
+//
+// SetNodeName sets the name of the environment node, the address other
+// nodes connect to. The name can not be changed once it is used
+//
+func (a *Act) SetNodeName(name string) error {
+	if name == "" {
+		return errors.New("empty node name")
+	}
+
+	a.nodes.mu.Lock()
+	defer a.nodes.mu.Unlock()
+
+	if a.nodes.name != "" && a.nodes.name != name {
+		return fmt.Errorf("node name is already %s", a.nodes.name)
+	}
+	a.nodes.name = name
+
+	return nil
+}
+
+//
+// Listen accepts connections of other nodes on the TCP address, or on the
+// unix socket if the address starts with "unix:"
+//
+func Listen(addr string) error {
+	return env.Listen(addr)
+}
+
+func (a *Act) Listen(addr string) error {
+	l, err := net.Listen(splitAddr(addr))
+	if err != nil {
+		return err
+	}
+
+	a.nodes.mu.Lock()
+	if a.nodes.closed {
+		a.nodes.mu.Unlock()
+		l.Close()
+		return ErrEnvShutdown
+	}
+	if a.nodes.name == "" {
+		a.nodes.name = listenerName(l)
+	}
+	a.nodes.listeners = append(a.nodes.listeners, l)
+	a.nodes.mu.Unlock()
+
+	go a.accept(l)
+
+	return nil
+}
+
+//
+// Connect connects to the node listening on the address. The connected node
+// is returned if the environment is already connected to the node
+//
+func Connect(addr string) (*Node, error) {
+	return env.Connect(addr)
+}
+
+func (a *Act) Connect(addr string) (*Node, error) {
+	conn, err := net.DialTimeout(network(addr), address(addr), handshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.handshake(conn, true)
+}
+
+//
+// Node returns the connected node by name or nil
+//
+func (a *Act) Node(name string) *Node {
+	a.nodes.mu.Lock()
+	defer a.nodes.mu.Unlock()
+
+	return a.nodes.byName[name]
+}
+
+//
+// Nodes returns names of connected nodes
+//
+func Nodes() []string {
+	return env.Nodes()
+}
+
+func (a *Act) Nodes() []string {
+	a.nodes.mu.Lock()
+	names := make([]string, 0, len(a.nodes.byName))
+	for name := range a.nodes.byName {
+		names = append(names, name)
+	}
+	a.nodes.mu.Unlock()
+
+	sort.Strings(names)
+
+	return names
+}
+
+//
+// Node returns the name of the node of the process
+//
+func (pid *Pid) Node() string {
+	if pid == nil {
+		return ""
+	}
+
+	if pid.node != nil {
+		return pid.node.name
+	}
+
+	if pid.wireNode != "" {
+		return pid.wireNode
+	}
+
+	return pid.env.NodeName()
+}
+
+// ---------------------------------------------------------------------------
+//
+// Name returns the name of the node
+//
+func (n *Node) Name() string {
+	return n.name
+}
+
+//
+// IsAlive checks if the connection to the node is open
+//
+func (n *Node) IsAlive() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return !n.closed
+}
+
+//
+// Close closes the connection to the node
+//
+func (n *Node) Close() error {
+	n.close()
+
+	return nil
+}
+
+//
+// Whereis returns the pid registered with the name on the node
+//
+func (n *Node) Whereis(name interface{}) (*Pid, error) {
+	return n.WhereisPrefix("", name)
+}
+
+//
+// WhereisPrefix returns the pid registered with the prefix and name on
+// the node, nil if the name is not registered
+//
+func (n *Node) WhereisPrefix(prefix string, name interface{}) (*Pid, error) {
+	r, err := n.request(context.Background(),
+		&frame{Type: frameWhereis, Prefix: prefix, Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := decodeError(r.Err); err != nil {
+		return nil, err
+	}
+
+	pid, _ := r.Data.(*Pid)
+
+	return pid, nil
+}
+
+// ---------------------------------------------------------------------------
+func (a *Act) accept(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go a.handshake(conn, false)
+	}
+}
+
+//
+// handshake exchanges node names and starts reading frames of the node.
+// The connecting side sends its name first with the name of its codec,
+// the codec is used by both sides after hello
+//
+func (a *Act) handshake(conn net.Conn, connecting bool) (*Node, error) {
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	codec := a.getCodec()
+	hello := &frame{Type: frameHello, Node: a.NodeName()}
+
+	if connecting {
+		hello.Codec = codec.Name()
+		if err := writeFrame(conn, GobCodec, hello); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	f, err := readFrame(conn, GobCodec)
+	if err == nil && (f.Type != frameHello || f.Node == "") {
+		err = fmt.Errorf("bad hello frame from %s", conn.RemoteAddr())
+	}
+	if err == nil && f.Node == hello.Node {
+		err = fmt.Errorf("node %s %w", f.Node, errConnectSelf)
+		if !connecting {
+			hello.Err = encodeError(err)
+			writeFrame(conn, GobCodec, hello)
+		}
+	}
+	if err == nil && f.Err != nil {
+		if old := a.Node(f.Node); old != nil {
+			conn.Close()
+			return old, nil
+		}
+		err = decodeError(f.Err)
+	}
+	if err == nil && !connecting {
+		var ok bool
+		if codec, ok = types.codec(f.Codec); !ok {
+			err = fmt.Errorf("unknown codec %q of node %s", f.Codec, f.Node)
+			hello.Err = encodeError(err)
+			writeFrame(conn, GobCodec, hello)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	n := a.newNode(f.Node, conn, codec)
+
+	old, err := a.nodes.add(n)
+	if err != nil {
+		if !connecting {
+			hello.Err = encodeError(err)
+			writeFrame(conn, GobCodec, hello)
+		}
+		conn.Close()
+
+		if old != nil {
+			return old, nil
+		}
+		return nil, err
+	}
+
+	if !connecting {
+		if err := writeFrame(conn, GobCodec, hello); err != nil {
+			a.nodes.remove(n)
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	go n.run()
+
+	n.syncGlobals()
+	a.nodes.notify(n.name, NodeUp{n.name})
+
+	return n, nil
+}
+
+func (a *Act) newNode(name string, conn net.Conn, codec Codec) *Node {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Node{
+		env:      a,
+		name:     name,
+		conn:     conn,
+		codec:    codec,
+		ctx:      ctx,
+		cancel:   cancel,
+		requests: make(map[uint64]chan *frame),
+		pids:     make(map[uint64]*Pid),
+	}
+}
+
+//
+// run reads frames until the connection is closed
+//
+func (n *Node) run() {
+	defer n.close()
+
+	for {
+		f, err := readFrame(n.conn, n.codec)
+		if err != nil {
+			n.log().Debug("node disconnected",
+				"node", n.name, "error", err)
+			return
+		}
+
+		f.Name = n.env.resolvePids(f.Name)
+		f.Data = n.env.resolvePids(f.Data)
+
+		n.dispatch(f)
+	}
+}
+
+func (n *Node) dispatch(f *frame) {
+	a := n.env
+
+	switch f.Type {
+
+	case frameCall:
+		go n.handleCall(f)
+
+	case frameCast:
+		// the read loop does not wait for room, the sender waits for room
+		// of the full mailbox with MailboxBlock policy
+		pid := a.procs.lookup(f.To)
+		if pid == nil {
+			n.reply(f.Ref, nil, GsNoProcError)
+			return
+		}
+		space, err := pid.mbox.put(userPriority(f.Prio),
+			&genReq{f.Data, context.Background()})
+		if space != nil {
+			err = errMailboxWait
+		}
+		n.reply(f.Ref, nil, err)
+
+	case frameStop:
+		go func() {
+			err := a.procs.lookup(f.To).StopReason(decodeError(f.Err))
+			n.reply(f.Ref, nil, err)
+		}()
+
+	case frameWhereis:
+		n.reply(f.Ref, a.WhereisPrefix(f.Prefix, f.Name), nil)
+
+	case frameHeartbeat:
+		n.reply(f.Ref, nil, nil)
+
+	case frameGlobalRegister, frameGlobalUnregister, frameGlobalSync:
+		n.handleGlobal(f)
+
+	case frameSpawn:
+		go n.handleSpawn(f)
+
+	case frameWatch:
+		if pid := a.procs.lookup(f.To); pid == nil || !pid.addNode(n) {
+			n.send(&frame{Type: frameExited,
+				To: f.To, Err: encodeError(GsNoProcError)})
+		}
+
+	case frameExited:
+		n.mu.Lock()
+		pid := n.pids[f.To]
+		delete(n.pids, f.To)
+		n.mu.Unlock()
+
+		if pid != nil {
+			pid.exitRemote(decodeError(f.Err))
+		}
+
+	case frameExitSignal:
+		from, _ := f.Data.(*Pid)
+		if pid := a.procs.lookup(f.To); pid != nil && from != nil {
+			pid.exitSignal(from, decodeError(f.Err))
+		}
+
+	case frameReply:
+		n.mu.Lock()
+		ch := n.requests[f.Ref]
+		n.mu.Unlock()
+
+		if ch != nil {
+			ch <- f
+		}
+	}
+}
+
+func (n *Node) handleCall(f *frame) {
+	ctx := WithPriority(n.ctx, f.Prio)
+	if !f.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, f.Deadline)
+		defer cancel()
+	}
+
+	reply, err := n.env.procs.lookup(f.To).CallContext(ctx, f.Data)
+
+	n.reply(f.Ref, reply, err)
+}
+
+//
+// reply sends the reply, encoding errors of the reply are sent instead
+//
+func (n *Node) reply(ref uint64, data Term, err error) {
+	if t, ok := data.(typedTerm); ok {
+		data = t.term()
+	}
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Ptr && v.IsNil() {
+		data = nil // nil pointers are not encodable
+	}
+
+	f := &frame{Type: frameReply, Ref: ref, Data: data, Err: encodeError(err)}
+
+	if err := n.send(f); err != nil && n.IsAlive() {
+		n.send(&frame{Type: frameReply, Ref: ref,
+			Err: encodeError(fmt.Errorf("node %s: %w", n.env.NodeName(), err))})
+	}
+}
+
+//
+// send writes the frame, the connection is closed on write error
+//
+func (n *Node) send(f *frame) error {
+	return n.sendContext(context.Background(), f)
+}
+
+//
+// sendContext writes the frame until ctx is done or the write timeout
+// expires. The frame not written by the deadline of ctx is not sent,
+// the partly written frame breaks the connection. The node not reading
+// frames by the write timeout is disconnected
+//
+func (n *Node) sendContext(ctx context.Context, f *frame) error {
+	b, err := encodeFrame(n.codec, f)
+	if err != nil {
+		return err
+	}
+
+	n.wmu.Lock()
+	defer n.wmu.Unlock()
+
+	deadline := time.Now().Add(n.writeTimeout())
+	d, ok := ctx.Deadline()
+	byCtx := ok && d.Before(deadline)
+	if byCtx {
+		deadline = d
+	}
+	n.conn.SetWriteDeadline(deadline)
+
+	written, err := n.conn.Write(b)
+	e, ok := err.(net.Error)
+	switch {
+	case err == nil:
+		return nil
+	case !ok:
+		return err
+	case !e.Timeout():
+		go n.close()
+		return GsNoProcError
+	case byCtx && written == 0:
+		return context.DeadlineExceeded
+	}
+
+	n.log().Warn("node write timeout", "node", n.name)
+	go n.closeReason(fmt.Errorf("%w: write timeout", ErrNoConnection))
+
+	if byCtx {
+		return context.DeadlineExceeded
+	}
+	return GsNoProcError
+}
+
+//
+// writeTimeout returns the time limit of a frame write
+//
+func (n *Node) writeTimeout() time.Duration {
+	n.env.nodes.mu.Lock()
+	defer n.env.nodes.mu.Unlock()
+
+	if c := n.env.nodes.cluster; c != nil {
+		return c.opts.Timeout
+	}
+
+	return writeTimeout
+}
+
+//
+// request sends the frame and waits for the reply
+//
+func (n *Node) request(ctx context.Context, f *frame) (*frame, error) {
+	ch := make(chan *frame, 1)
+
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return nil, GsNoProcError
+	}
+	n.serial++
+	f.Ref = n.serial
+	n.requests[f.Ref] = ch
+	n.mu.Unlock()
+
+	defer func() {
+		n.mu.Lock()
+		delete(n.requests, f.Ref)
+		n.mu.Unlock()
+	}()
+
+	if err := n.sendContext(ctx, f); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-ch:
+		return r, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.ctx.Done():
+		return nil, GsNoProcError
+	}
+}
+
+func (n *Node) call(ctx context.Context, pid *Pid, data Term) (Term, error) {
+	f := &frame{Type: frameCall, To: pid.id, Data: data, Prio: priorityOf(ctx)}
+	if d, ok := ctx.Deadline(); ok {
+		f.Deadline = d
+	}
+
+	r, err := n.request(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Data, decodeError(r.Err)
+}
+
+//
+// cast waits until the node queues the message. If the mailbox with
+// MailboxBlock policy is full the cast is repeated until ctx is done,
+// ErrMailboxFull is returned at once if wait is false
+//
+func (n *Node) cast(
+	ctx context.Context,
+	pid *Pid,
+	data Term,
+	wait bool) error {
+
+	f := &frame{Type: frameCast, To: pid.id, Data: data, Prio: priorityOf(ctx)}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := n.request(ctx, f)
+		if err != nil {
+			return err
+		}
+
+		err = decodeError(r.Err)
+		if !errors.Is(err, errMailboxWait) {
+			return err
+		}
+		if !wait {
+			return ErrMailboxFull
+		}
+
+		select {
+		case <-time.After(castRetryInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-n.ctx.Done():
+			return GsNoProcError
+		}
+	}
+}
+
+func (n *Node) stop(ctx context.Context, pid *Pid, reason error) error {
+	r, err := n.request(ctx,
+		&frame{Type: frameStop, To: pid.id, Err: encodeError(reason)})
+	if err != nil {
+		return err
+	}
+
+	return decodeError(r.Err)
+}
+
+//
+// pid returns the remote pid with the id, the same pid for the same id
+//
+func (n *Node) pid(id uint64) *Pid {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if pid, ok := n.pids[id]; ok {
+		return pid
+	}
+
+	pid := &Pid{id: id, env: n.env, node: n, mbox: closedMailbox()}
+	if n.closed {
+		pid.exited = true
+	} else {
+		n.pids[id] = pid
+	}
+
+	return pid
+}
+
+//
+// close closes the connection, remote pids exit with ErrNoConnection
+//
+func (n *Node) close() {
+	n.closeReason(ErrNoConnection)
+}
+
+//
+// closeReason closes the connection, remote pids exit with the reason and
+// processes monitoring the node receive NodeDown
+//
+func (n *Node) closeReason(reason error) {
+	n.mu.Lock()
+	if n.closed {
+		n.mu.Unlock()
+		return
+	}
+	n.closed = true
+	pids := n.pids
+	n.pids = nil
+	n.mu.Unlock()
+
+	n.cancel()
+	n.conn.Close()
+	n.env.nodes.remove(n)
+	n.env.globals.removeNode(n)
+
+	for _, pid := range pids {
+		pid.exitRemote(reason)
+	}
+
+	n.env.nodes.notify(n.name, NodeDown{n.name, reason})
+}
+
+func (n *Node) log() Logger {
+	return n.env.log()
+}
+
+// ---------------------------------------------------------------------------
+//
+// watchRemote asks the node of the remote process to notify when
+// the process exits, links and monitors of the pid are notified then,
+// names and groups of the pid are removed
+//
+func (pid *Pid) watchRemote() {
+	if pid != nil && pid.node != nil {
+		pid.node.send(&frame{Type: frameWatch, To: pid.id})
+	}
+}
+
+//
+// addNode adds the node watching the local process
+//
+func (pid *Pid) addNode(n *Node) bool {
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	if pid.exited {
+		return false
+	}
+
+	if pid.nodes == nil {
+		pid.nodes = make(map[*Node]struct{})
+	}
+	pid.nodes[n] = struct{}{}
+
+	return true
+}
+
+//
+// exitRemote marks the remote process as exited: names and groups of
+// the pid are removed, links and monitors are notified
+//
+func (pid *Pid) exitRemote(reason error) {
+	pid.unregisterNames()
+	pid.leaveGroups()
+	pid.exit(reason)
+}
+
+// ---------------------------------------------------------------------------
+//
+// resolvePid returns the pid of the process for the decoded pid
+//
+func (a *Act) resolvePid(p *Pid) *Pid {
+	if p.id == 0 {
+		return nil // encoded nil pid
+	}
+
+	if p.wireNode == "" || p.wireNode == a.NodeName() {
+		if pid := a.procs.lookup(p.id); pid != nil {
+			return pid
+		}
+		return a.deadPid(p.id, "")
+	}
+
+	if n := a.Node(p.wireNode); n != nil {
+		return n.pid(p.id)
+	}
+
+	return a.deadPid(p.id, p.wireNode)
+}
+
+//
+// deadPid returns the pid of not existing process
+//
+func (a *Act) deadPid(id uint64, node string) *Pid {
+	return &Pid{
+		id:       id,
+		env:      a,
+		wireNode: node,
+		mbox:     closedMailbox(),
+		exited:   true,
+	}
+}
+
+// ---------------------------------------------------------------------------
+//
+// localName returns the node name, generates it if not set
+//
+func (t *nodeTable) localName() string {
+	if t.name == "" {
+		b := make([]byte, 8)
+		rand.Read(b)
+		t.name = "node-" + hex.EncodeToString(b)
+	}
+
+	return t.name
+}
+
+//
+// add adds the connected node, the node connected before is returned with
+// error if connected
+//
+func (t *nodeTable) add(n *Node) (*Node, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil, ErrEnvShutdown
+	}
+
+	if old, ok := t.byName[n.name]; ok {
+		return old, fmt.Errorf("node %s already connected", n.name)
+	}
+
+	if t.byName == nil {
+		t.byName = make(map[string]*Node)
+	}
+	t.byName[n.name] = n
+
+	return nil, nil
+}
+
+func (t *nodeTable) remove(n *Node) {
+	t.mu.Lock()
+	if t.byName[n.name] == n {
+		delete(t.byName, n.name)
+	}
+	t.mu.Unlock()
+}
+
+//
+// closeAll closes listeners and connections to nodes
+//
+func (t *nodeTable) closeAll() {
+	t.mu.Lock()
+	t.closed = true
+	if t.cluster != nil {
+		t.cluster.cancel()
+	}
+	listeners := t.listeners
+	t.listeners = nil
+	nodes := make([]*Node, 0, len(t.byName))
+	for _, n := range t.byName {
+		nodes = append(nodes, n)
+	}
+	t.mu.Unlock()
+
+	for _, l := range listeners {
+		l.Close()
+	}
+
+	for _, n := range nodes {
+		n.close()
+	}
+}
+
+// ---------------------------------------------------------------------------
+func splitAddr(addr string) (string, string) {
+	return network(addr), address(addr)
+}
+
+func network(addr string) string {
+	if strings.HasPrefix(addr, "unix:") {
+		return "unix"
+	}
+
+	return "tcp"
+}
+
+func address(addr string) string {
+	return strings.TrimPrefix(addr, "unix:")
+}
+
+func listenerName(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return "unix:" + l.Addr().String()
+	}
+
+	addr, ok := l.Addr().(*net.TCPAddr)
+	if !ok || !addr.IP.IsUnspecified() {
+		return l.Addr().String()
+	}
+
+	// other hosts can not dial the unspecified address
+	host, err := os.Hostname()
+	if err != nil {
+		return l.Addr().String()
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(addr.Port))
+}
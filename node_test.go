@@ -0,0 +1,534 @@
+package act
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+//
+// echoes calls, pings the pid of the request
+//
+type gsEcho struct {
+	GenServerImpl
+	block chan struct{}
+}
+
+type pingReq struct {
+	From *Pid
+	Msg  string
+}
+
+func init() {
+	RegisterType("act.pingReq", pingReq{})
+}
+
+func (s *gsEcho) HandleCall(req Term, from From) Term {
+	switch req := req.(type) {
+	case pingReq:
+		req.From.Cast(req.Msg)
+		return &GsCallReply{req.From.Node()}
+	case string:
+		switch req {
+		case "panic":
+			panic("remote boom")
+		case "block":
+			<-s.block
+		case "error":
+			return errors.New("echo error")
+		case "self":
+			return &GsCallReply{s.Self()}
+		}
+	}
+
+	return &GsCallReply{req}
+}
+
+//
+// two connected environments, echo process registered on the first one
+//
+func startNodes(t *testing.T, addr string) (*Act, *Act, *Node, *gsEcho) {
+	a := NewEnv()
+	b := NewEnv()
+	t.Cleanup(func() {
+		b.Shutdown(contextTimeout(t))
+		a.Shutdown(contextTimeout(t))
+	})
+
+	if err := a.Listen(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	echo := &gsEcho{block: make(chan struct{})}
+	if _, err := a.SpawnOpts(echo, &Opts{Name: "echo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := b.Connect(a.NodeName())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a, b, node, echo
+}
+
+func contextTimeout(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	t.Cleanup(cancel)
+
+	return ctx
+}
+
+func remoteEcho(t *testing.T, node *Node) *Pid {
+	pid, err := node.Whereis("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid == nil {
+		t.Fatal("echo must be registered")
+	}
+
+	return pid
+}
+
+func TestNodeCall(t *testing.T) {
+	a, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	if node.Name() != a.NodeName() {
+		t.Errorf("want node %s, got %s", a.NodeName(), node.Name())
+	}
+	if names := a.Nodes(); len(names) != 1 || names[0] != b.NodeName() {
+		t.Errorf("want node %s connected, got %v", b.NodeName(), names)
+	}
+
+	pid := remoteEcho(t, node)
+	if pid.Node() != a.NodeName() || pid.Id() != a.Whereis("echo").Id() {
+		t.Errorf("bad remote pid: #%d at %s", pid.Id(), pid.Node())
+	}
+	if again := remoteEcho(t, node); again != pid {
+		t.Error("remote pid must be the same for the same process")
+	}
+
+	r, err := pid.Call("hello")
+	if err != nil || r != "hello" {
+		t.Errorf("want 'hello', got %#v, %v", r, err)
+	}
+
+	if err := pid.Cast("cast"); err != nil {
+		t.Error(err)
+	}
+
+	// pid of the remote node
+	r, err = pid.Call("self")
+	if err != nil || r != pid {
+		t.Errorf("want remote pid, got %#v, %v", r, err)
+	}
+
+	if pid, _ := node.Whereis("unknown"); pid != nil {
+		t.Errorf("want nil pid, got #%d", pid.Id())
+	}
+}
+
+func TestNodePidInMessage(t *testing.T) {
+	_, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	recv, err := b.Spawn(&gsOrder{release: make(chan struct{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recv.Stop()
+
+	pid := remoteEcho(t, node)
+
+	r, err := pid.Call(pingReq{From: recv, Msg: "pong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != b.NodeName() {
+		t.Errorf("want pid of node %s, got %#v", b.NodeName(), r)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if order := orderOf(t, recv); !equalOrder(order, []string{"pong"}) {
+		t.Errorf("want pong, got %v", order)
+	}
+}
+
+func TestNodeErrors(t *testing.T) {
+	a, _, node, echo := startNodes(t, "127.0.0.1:0")
+	a.SetLogger(new(testLogger))
+
+	pid := remoteEcho(t, node)
+
+	if _, err := pid.Call("error"); err == nil || err.Error() != "echo error" {
+		t.Errorf("want echo error, got %v", err)
+	}
+
+	_, err := pid.CallTimeout("block", 50*time.Millisecond)
+	if !IsTimeoutError(err) {
+		t.Errorf("want timeout, got %v", err)
+	}
+	echo.block <- struct{}{}
+
+	if _, err := pid.Call("panic"); !IsCrash(err) {
+		t.Errorf("want crash, got %v", err)
+	}
+
+	if _, err := pid.Call("hello"); !IsNoProcError(err) {
+		t.Errorf("want GsNoProcError, got %v", err)
+	}
+}
+
+func TestNodeStop(t *testing.T) {
+	a, _, node, _ := startNodes(t, "127.0.0.1:0")
+
+	pid := remoteEcho(t, node)
+
+	if err := pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if a.Whereis("echo") != nil {
+		t.Error("remote process must be stopped")
+	}
+
+	if err := pid.Stop(); !IsNoProcError(err) {
+		t.Errorf("want GsNoProcError, got %v", err)
+	}
+}
+
+func TestNodeDisconnect(t *testing.T) {
+	a, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	pid := remoteEcho(t, node)
+
+	events := make(chan Term, 1)
+	w, err := b.Spawn(&gsWatch{events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	ref := w.Monitor(pid)
+
+	a.Shutdown(contextTimeout(t))
+
+	select {
+	case e := <-events:
+		down, ok := e.(Down)
+		if !ok || down.Ref != ref || down.Pid != pid ||
+			!errors.Is(down.Reason, ErrNoConnection) {
+			t.Errorf("bad Down: %#v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Down must be sent on disconnect")
+	}
+
+	if _, err := pid.Call("hello"); !IsNoProcError(err) {
+		t.Errorf("want GsNoProcError, got %v", err)
+	}
+	if node.IsAlive() || len(b.Nodes()) != 0 {
+		t.Error("node must be disconnected")
+	}
+}
+
+func TestNodeUnixSocket(t *testing.T) {
+	addr := "unix:" + filepath.Join(t.TempDir(), "node.sock")
+
+	a, _, node, _ := startNodes(t, addr)
+
+	if a.NodeName() != addr {
+		t.Errorf("want node name %s, got %s", addr, a.NodeName())
+	}
+
+	r, err := remoteEcho(t, node).Call("hello")
+	if err != nil || r != "hello" {
+		t.Errorf("want 'hello', got %#v, %v", r, err)
+	}
+}
+
+func TestNodeConnectTwice(t *testing.T) {
+	a, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	again, err := b.Connect(a.NodeName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != node {
+		t.Error("want connected node")
+	}
+}
+
+func TestNodeCastMailbox(t *testing.T) {
+	a, _, node, _ := startNodes(t, "127.0.0.1:0")
+	a.SetLogger(new(testLogger))
+
+	// the full mailbox of the process on node a, remote pid of it
+	full := func(name string, policy MailboxPolicy) (*Pid, *Pid, *gsOrder) {
+		s := &gsOrder{release: make(chan struct{})}
+		pid, err := a.SpawnOpts(s, &Opts{
+			Name: name, ChanSize: 1, Mailbox: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pid.Cast("wait")
+		time.Sleep(50 * time.Millisecond)
+		pid.Cast("a")
+
+		remote, err := node.Whereis(name)
+		if err != nil || remote == nil {
+			t.Fatal("want remote pid", err)
+		}
+
+		return pid, remote, s
+	}
+
+	pid, remote, s := full("block", MailboxBlock)
+
+	if err := remote.TryCast("b"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("TryCast: want ErrMailboxFull, got %v", err)
+	}
+	if err := remote.CastTimeout("b", 50*time.Millisecond); !errors.Is(
+		err, ErrMailboxFull) {
+		t.Errorf("CastTimeout: want ErrMailboxFull, got %v", err)
+	}
+
+	// Cast waits for room
+	done := make(chan error, 1)
+	go func() { done <- remote.Cast("b") }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("cast must wait for room, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(s.release)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if order := orderOf(t, pid); !equalOrder(order, []string{"a", "b"}) {
+		t.Errorf("want [a b], got %v", order)
+	}
+
+	// priority of the system lane from the wire
+	err := node.send(&frame{Type: frameCast, To: pid.Id(), Data: "bad", Prio: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if !node.IsAlive() {
+		t.Error("node must stay connected")
+	}
+
+	_, remote, s = full("dropNewest", MailboxDropNewest)
+	if err := remote.Cast("b"); err != nil {
+		t.Errorf("cast must be dropped silently, got %v", err)
+	}
+	if err := remote.TryCast("b"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("TryCast: want ErrMailboxFull, got %v", err)
+	}
+	close(s.release)
+
+	_, remote, s = full("failFast", MailboxFailFast)
+	if err := remote.Cast("b"); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("Cast: want ErrMailboxFull, got %v", err)
+	}
+	close(s.release)
+}
+
+func TestNodeRemoteMonitor(t *testing.T) {
+	a, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	pid := remoteEcho(t, node)
+
+	events := make(chan Term, 10)
+	w, err := b.Spawn(&gsWatch{events: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	ref := w.Monitor(pid)
+	time.Sleep(50 * time.Millisecond)
+
+	if err := a.Whereis("echo").Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	e := nextEvent(t, events)
+	if down, ok := e.(Down); !ok || down.Ref != ref || down.Pid != pid ||
+		!errors.Is(down.Reason, Shutdown) {
+		t.Errorf("bad Down: %#v", e)
+	}
+	if !node.IsAlive() {
+		t.Error("node must stay connected")
+	}
+
+	// monitor of exited remote process
+	ref = w.Monitor(node.pid(pid.Id() + 1000))
+	e = nextEvent(t, events)
+	if down, ok := e.(Down); !ok || down.Ref != ref || !IsNoProcError(down.Reason) {
+		t.Errorf("want Down with GsNoProcError, got %#v", e)
+	}
+}
+
+func TestNodeRemoteLink(t *testing.T) {
+	a, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	// remote process exits
+	events := make(chan Term, 10)
+	w, err := b.SpawnOpts(&gsWatch{events: events}, &Opts{TrapExit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	pid := remoteEcho(t, node)
+	if err := w.Link(pid); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	a.Whereis("echo").StopReason(errStop)
+
+	e := nextEvent(t, events)
+	// errors other than known ones are restored with the same message
+	if exit, ok := e.(Exit); !ok || exit.Pid != pid ||
+		exit.Reason.Error() != errStop.Error() {
+		t.Errorf("bad Exit: %#v", e)
+	}
+
+	// local process exits, remote one traps exits
+	remoteEvents := make(chan Term, 10)
+	rw, err := a.SpawnOpts(&gsWatch{events: remoteEvents},
+		&Opts{Name: "watch", TrapExit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Stop()
+
+	remote, err := node.Whereis("watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := b.Spawn(&gsWatch{events: make(chan Term, 10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Link(remote); err != nil {
+		t.Fatal(err)
+	}
+	local.StopReason(errStop)
+
+	e = nextEvent(t, remoteEvents)
+	if exit, ok := e.(Exit); !ok || exit.Pid.Id() != local.Id() ||
+		exit.Pid.Node() != b.NodeName() || exit.Reason.Error() != errStop.Error() {
+		t.Errorf("bad Exit: %#v", e)
+	}
+}
+
+func TestNodeRemoteNames(t *testing.T) {
+	_, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	pid := remoteEcho(t, node)
+
+	if err := b.Register("remote-echo", pid); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Join("g", pid); err != nil {
+		t.Fatal(err)
+	}
+
+	node.Close()
+
+	if b.Whereis("remote-echo") != nil {
+		t.Error("name of remote pid must be unregistered")
+	}
+	if members := b.Members("g"); len(members) != 0 {
+		t.Errorf("want no members, got %v", members)
+	}
+}
+
+// waitDisconnect waits for the node is disconnected
+func waitDisconnect(t *testing.T, node *Node, timeout time.Duration) {
+	for end := time.Now().Add(timeout); time.Now().Before(end); {
+		if !node.IsAlive() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("node not reading frames must be disconnected")
+}
+
+func TestNodeWriteTimeout(t *testing.T) {
+	b := NewEnv()
+	defer b.Shutdown(contextTimeout(t))
+	b.SetLogger(new(testLogger))
+
+	node, err := b.Connect(fakeNode(t, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// larger than socket buffers
+	big := strings.Repeat("x", 32<<20)
+
+	started := time.Now()
+	_, err = node.pid(1).CallTimeout(big, time.Second)
+	if err != GsTimeoutError {
+		t.Errorf("want GsTimeoutError, got %v", err)
+	}
+	if d := time.Since(started); d > 3*time.Second {
+		t.Errorf("call must fail in time, failed in %v", d)
+	}
+
+	waitDisconnect(t, node, time.Second)
+}
+
+func TestNodeRemoteNamesExit(t *testing.T) {
+	_, b, node, _ := startNodes(t, "127.0.0.1:0")
+
+	pid := remoteEcho(t, node)
+
+	if err := b.Register("remote-echo", pid); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Join("g", pid); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pid.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100 && b.Whereis("remote-echo") != nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if b.Whereis("remote-echo") != nil {
+		t.Error("name of remote pid must be unregistered")
+	}
+	if members := b.Members("g"); len(members) != 0 {
+		t.Errorf("want no members, got %v", members)
+	}
+	if pid.IsAlive() {
+		t.Error("remote pid must be exited")
+	}
+
+	node.mu.Lock()
+	_, cached := node.pids[pid.Id()]
+	node.mu.Unlock()
+
+	if cached {
+		t.Error("exited remote pid must be removed from the node")
+	}
+}
@@ -0,0 +1,112 @@
+package act
+
+import (
+	"sort"
+	"sync"
+)
+
+//
+// procTable stores all live processes of the environment
+//
+type procTable struct {
+	mu     sync.RWMutex
+	pids   map[uint64]*Pid
+	closed bool // no processes are added after shutdown
+}
+
+//
+// Processes returns information of all live processes ordered by id
+//
+func Processes() []ProcessInfo {
+	return env.Processes()
+}
+
+func (a *Act) Processes() []ProcessInfo {
+	pids := a.procs.list()
+
+	infos := make([]ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		// skip processes exited after listing
+		if info, err := pid.Info(); err == nil {
+			infos = append(infos, info)
+		}
+	}
+
+	return infos
+}
+
+//
+// Count returns the number of live processes
+//
+func Count() int {
+	return env.Count()
+}
+
+func (a *Act) Count() int {
+	return a.procs.count()
+}
+
+// ---------------------------------------------------------------------------
+func (t *procTable) add(pid *Pid) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return false
+	}
+
+	if t.pids == nil {
+		t.pids = make(map[uint64]*Pid)
+	}
+	t.pids[pid.id] = pid
+
+	return true
+}
+
+func (t *procTable) remove(pid *Pid) {
+	t.mu.Lock()
+	delete(t.pids, pid.id)
+	t.mu.Unlock()
+}
+
+func (t *procTable) lookup(id uint64) *Pid {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.pids[id]
+}
+
+func (t *procTable) close() {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+}
+
+func (t *procTable) isClosed() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.closed
+}
+
+func (t *procTable) count() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.pids)
+}
+
+func (t *procTable) list() []*Pid {
+	t.mu.RLock()
+	pids := make([]*Pid, 0, len(t.pids))
+	for _, pid := range t.pids {
+		pids = append(pids, pid)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(pids, func(i, j int) bool {
+		return pids[i].Id() < pids[j].Id()
+	})
+
+	return pids
+}
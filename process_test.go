@@ -0,0 +1,88 @@
+package act
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProcesses(t *testing.T) {
+	a := NewEnv()
+
+	if n := a.Count(); n != 0 {
+		t.Fatalf("want 0 processes in new env, got %d", n)
+	}
+
+	named, err := a.SpawnOpts(new(gsInfo), &Opts{Name: "named"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	anon1, err := a.Spawn(new(gsInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	anon2, err := a.Spawn(new(gsInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := a.Count(); n != 3 {
+		t.Errorf("want 3 processes, got %d", n)
+	}
+
+	infos := a.Processes()
+	if len(infos) != 3 {
+		t.Fatalf("want 3 processes, got %d", len(infos))
+	}
+	for i, pid := range []*Pid{named, anon1, anon2} {
+		if infos[i].Pid != pid {
+			t.Errorf("want pid #%d at %d, got #%d", pid.Id(), i, infos[i].Id)
+		}
+	}
+	if infos[0].Name != "named" {
+		t.Errorf("want name 'named', got %#v", infos[0].Name)
+	}
+
+	anon1.Stop()
+
+	infos = a.Processes()
+	if a.Count() != 2 || len(infos) != 2 || infos[1].Pid != anon2 {
+		t.Errorf("stopped process must be removed: %+v", infos)
+	}
+
+	named.Stop()
+	anon2.Stop()
+
+	if n := a.Count(); n != 0 {
+		t.Errorf("want 0 processes, got %d", n)
+	}
+}
+
+type gsInitFail struct {
+	GenServerImpl
+}
+
+func (s *gsInitFail) Init(args ...interface{}) Term {
+	return &GsInitStop{errors.New("init failed")}
+}
+
+func TestProcessesInitFailed(t *testing.T) {
+	a := NewEnv()
+
+	if _, err := a.Spawn(new(gsInitFail)); err == nil {
+		t.Fatal("init must fail")
+	}
+
+	pid, err := a.SpawnOpts(new(gsInfo), &Opts{Name: "dup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	if _, err := a.SpawnOpts(new(gsInfo), &Opts{Name: "dup"}); err == nil {
+		t.Fatal("name must be registered")
+	}
+
+	if n := a.Count(); n != 1 {
+		t.Errorf("want 1 live process, got %d", n)
+	}
+}
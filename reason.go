@@ -0,0 +1,65 @@
+package act
+
+import (
+	"errors"
+	"fmt"
+)
+
+//
+// Reasons of the process exit. The reason passed to Terminate, returned
+// from Spawn and delivered in Down and Exit messages is an error: one of
+// the reasons below, *Crash or any error returned by the process callbacks.
+// Use errors.Is and errors.As to check the reason
+//
+var (
+	// Normal is the reason of the normal process exit. Linked processes are
+	// not stopped when the process exits with this reason
+	Normal = errors.New("normal")
+	// Shutdown is the reason the process is stopped with by Stop or by its
+	// supervisor
+	Shutdown = errors.New("shutdown")
+	// Killed is the reason the process is stopped with when a linked
+	// process exits. The reason of the linked process is wrapped too
+	Killed = errors.New("killed")
+	// ErrBadReply is the reason the process is stopped with when a callback
+	// returns an unexpected result
+	ErrBadReply = errors.New("bad reply")
+)
+
+//
+// Crash is the reason of the process terminated by panic
+//
+type Crash struct {
+	Panic interface{}
+	Stack []byte
+}
+
+func (c *Crash) Error() string {
+	return fmt.Sprintf("crashed: %#v", c.Panic)
+}
+
+//
+// IsCrash checks if the reason is the process crash
+//
+func IsCrash(reason error) bool {
+	var crash *Crash
+	return errors.As(reason, &crash)
+}
+
+//
+// reasonOrNormal returns Normal for nil reason
+//
+func reasonOrNormal(reason error) error {
+	if reason == nil {
+		return Normal
+	}
+
+	return reason
+}
+
+//
+// isAbnormal checks if the reason is neither Normal nor Shutdown
+//
+func isAbnormal(reason error) bool {
+	return !errors.Is(reason, Normal) && !errors.Is(reason, Shutdown)
+}
@@ -0,0 +1,177 @@
+package act
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errInit = errors.New("init failed")
+
+//
+// gsReason reports the reason passed to Terminate
+//
+type gsReason struct {
+	GenServerImpl
+	reasons chan error
+}
+
+func (s *gsReason) Init(args ...interface{}) Term {
+	if len(args) > 0 {
+		if args[0] == cmdCrash {
+			panic(cmdCrash)
+		}
+		return &GsInitStop{errInit}
+	}
+
+	return GsInitOk
+}
+
+func (s *gsReason) HandleCall(req Term, from From) Term {
+	switch req {
+	case cmdCrash:
+		panic(cmdCrash)
+	case cmdCallBadReply:
+		return false
+	}
+
+	return &GsCallStop{Reason: errStop, Reply: "stopped"}
+}
+
+func (s *gsReason) Terminate(reason error) {
+	s.reasons <- reason
+}
+
+func startReason(t *testing.T) (*Pid, chan error) {
+	s := &gsReason{reasons: make(chan error, 1)}
+
+	pid, err := Spawn(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pid, s.reasons
+}
+
+func waitReason(t *testing.T, reasons chan error) error {
+	select {
+	case reason := <-reasons:
+		return reason
+	case <-time.After(time.Second):
+		t.Fatal("Terminate is not called")
+	}
+
+	return nil
+}
+
+func TestReasonInitStop(t *testing.T) {
+	_, err := Spawn(&gsReason{reasons: make(chan error, 1)}, true)
+	if !errors.Is(err, errInit) {
+		t.Errorf("Spawn must return init stop reason, got %v", err)
+	}
+}
+
+func TestReasonInitCrash(t *testing.T) {
+	a := NewEnv()
+	a.SetLogger(new(testLogger))
+
+	s := &gsReason{reasons: make(chan error, 1)}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.Spawn(s, cmdCrash)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		var crash *Crash
+		if !errors.As(err, &crash) || crash.Panic != cmdCrash {
+			t.Errorf("Spawn must return *Crash, got %#v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Spawn must return when Init panics")
+	}
+
+	if n := a.Count(); n != 0 {
+		t.Errorf("want no processes, got %d", n)
+	}
+
+	select {
+	case reason := <-s.reasons:
+		t.Errorf("Terminate must not be called after Init, got %v", reason)
+	default:
+	}
+}
+
+func TestReasonStop(t *testing.T) {
+	pid, reasons := startReason(t)
+	pid.Stop()
+
+	if reason := waitReason(t, reasons); reason != Shutdown {
+		t.Errorf("want Shutdown reason, got %v", reason)
+	}
+}
+
+func TestReasonCallStop(t *testing.T) {
+	pid, reasons := startReason(t)
+
+	r, err := pid.Call(cmdTest)
+	if err != nil || r != "stopped" {
+		t.Errorf("unexpected reply: %#v, %v", r, err)
+	}
+
+	if reason := waitReason(t, reasons); reason != errStop {
+		t.Errorf("want %v reason, got %v", errStop, reason)
+	}
+}
+
+func TestReasonCrash(t *testing.T) {
+	pid, reasons := startReason(t)
+
+	_, err := pid.Call(cmdCrash)
+
+	var crash *Crash
+	if !errors.As(err, &crash) {
+		t.Fatalf("Call must return *Crash, got %#v", err)
+	}
+	if crash.Panic != cmdCrash {
+		t.Errorf("unexpected panic value: %#v", crash.Panic)
+	}
+
+	if reason := waitReason(t, reasons); !IsCrash(reason) {
+		t.Errorf("want crash reason, got %v", reason)
+	}
+}
+
+func TestReasonBadReply(t *testing.T) {
+	pid, reasons := startReason(t)
+
+	_, err := pid.Call(cmdCallBadReply)
+	if !errors.Is(err, ErrBadReply) {
+		t.Errorf("want ErrBadReply, got %v", err)
+	}
+
+	if reason := waitReason(t, reasons); !errors.Is(reason, ErrBadReply) {
+		t.Errorf("want ErrBadReply reason, got %v", reason)
+	}
+}
+
+func TestReasonKilled(t *testing.T) {
+	pid1, _ := startReason(t)
+	pid2, reasons := startReason(t)
+
+	if err := pid1.Link(pid2); err != nil {
+		t.Fatal(err)
+	}
+
+	pid1.Call(cmdCrash)
+
+	reason := waitReason(t, reasons)
+	if !errors.Is(reason, Killed) {
+		t.Errorf("want Killed reason, got %v", reason)
+	}
+	if !IsCrash(reason) {
+		t.Errorf("reason of linked process must be wrapped, got %v", reason)
+	}
+}
@@ -0,0 +1,189 @@
+package act
+
+import (
+	"hash/maphash"
+	"sync"
+)
+
+const registryShards = 64
+
+type regKey struct {
+	prefix string
+	name   interface{}
+}
+
+//
+// nameKey is the name owned by the process in the registry
+//
+type nameKey struct {
+	registry *registry
+	regKey
+}
+
+type regShard struct {
+	sync.RWMutex
+	prefixes map[string]RegMap
+}
+
+//
+// registry stores names of processes. Names are spread over shards by hash
+// of prefix and name, every shard has its own lock
+//
+type registry struct {
+	seed     maphash.Seed
+	shards   [registryShards]regShard
+	watchers regWatchers
+}
+
+func newRegistry() *registry {
+	r := &registry{seed: maphash.MakeSeed()}
+
+	for i := range r.shards {
+		r.shards[i].prefixes = make(map[string]RegMap)
+	}
+
+	return r
+}
+
+func (r *registry) shard(prefix string, name interface{}) *regShard {
+	h := maphash.Comparable(r.seed, regKey{prefix, name})
+
+	return &r.shards[h%registryShards]
+}
+
+//
+// register associates the name with pid if the name is not registered yet,
+// otherwise returns the registered pid. Returns nil pid if the process
+// exited. The pid keeps the names it owns to unregister them on exit
+//
+// Locks are taken in order: shard, then pid
+//
+func (r *registry) register(
+	prefix string,
+	name interface{},
+	pid *Pid) (*Pid, bool) {
+
+	s := r.shard(prefix, name)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if old, ok := s.prefixes[prefix][name]; ok {
+		return old, false
+	}
+
+	if !pid.addName(nameKey{r, regKey{prefix, name}}) {
+		return nil, false
+	}
+
+	names, ok := s.prefixes[prefix]
+	if !ok {
+		names = make(RegMap)
+		s.prefixes[prefix] = names
+	}
+	names[name] = pid
+
+	r.watchers.notify(Registered, prefix, name, pid)
+
+	return pid, true
+}
+
+func (r *registry) unregister(prefix string, name interface{}) {
+	s := r.shard(prefix, name)
+
+	s.Lock()
+	defer s.Unlock()
+
+	if pid, ok := s.prefixes[prefix][name]; ok {
+		pid.removeName(nameKey{r, regKey{prefix, name}})
+		s.remove(prefix, name)
+		r.watchers.notify(Unregistered, prefix, name, pid)
+	}
+}
+
+//
+// unregisterNames marks the pid as exited and removes all names the pid owns
+// in all registries. Names reused by other processes are not touched
+//
+func (pid *Pid) unregisterNames() {
+	pid.mu.Lock()
+	pid.exited = true
+	keys := pid.names
+	pid.names = nil
+	pid.mu.Unlock()
+
+	for key := range keys {
+		r := key.registry
+		s := r.shard(key.prefix, key.name)
+
+		s.Lock()
+		if s.prefixes[key.prefix][key.name] == pid {
+			s.remove(key.prefix, key.name)
+			r.watchers.notify(Unregistered, key.prefix, key.name, pid)
+		}
+		s.Unlock()
+	}
+}
+
+func (s *regShard) remove(prefix string, name interface{}) {
+	names := s.prefixes[prefix]
+	delete(names, name)
+	if len(names) == 0 {
+		delete(s.prefixes, prefix)
+	}
+}
+
+func (r *registry) whereis(prefix string, name interface{}) *Pid {
+	s := r.shard(prefix, name)
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.prefixes[prefix][name]
+}
+
+func (r *registry) whereare(prefix string) RegMap {
+	var pids RegMap
+
+	for i := range r.shards {
+		s := &r.shards[i]
+
+		s.RLock()
+		for name, pid := range s.prefixes[prefix] {
+			if pids == nil {
+				pids = make(RegMap)
+			}
+			pids[name] = pid
+		}
+		s.RUnlock()
+	}
+
+	return pids
+}
+
+// ---------------------------------------------------------------------------
+func (pid *Pid) addName(key nameKey) bool {
+	if pid == nil {
+		return false
+	}
+
+	pid.mu.Lock()
+	defer pid.mu.Unlock()
+
+	if pid.exited {
+		return false
+	}
+
+	if pid.names == nil {
+		pid.names = make(map[nameKey]struct{})
+	}
+	pid.names[key] = struct{}{}
+
+	return true
+}
+
+func (pid *Pid) removeName(key nameKey) {
+	pid.mu.Lock()
+	delete(pid.names, key)
+	pid.mu.Unlock()
+}
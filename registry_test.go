@@ -0,0 +1,155 @@
+package act
+
+import (
+	"testing"
+)
+
+//
+// gsReg registers additional names in Init
+//
+type gsReg struct {
+	GenServerImpl
+}
+
+func (s *gsReg) Init(args ...interface{}) Term {
+	for _, name := range args {
+		if err := RegisterPrefix("regGroup", name, s.Self()); err != nil {
+			return &GsInitStop{err}
+		}
+	}
+
+	return GsInitOk
+}
+
+func TestUnregisterOwnNames(t *testing.T) {
+	pid, err := SpawnPrefixName(new(gsReg), "regGroup", "own", "own1", "own2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(Whereare("regGroup")); n != 3 {
+		t.Fatalf("expected 3 names, got %d", n)
+	}
+
+	pid.Stop()
+
+	if pids := Whereare("regGroup"); len(pids) != 0 {
+		t.Errorf("names must be unregistered on exit: %v", pids)
+	}
+}
+
+func TestUnregisterReusedName(t *testing.T) {
+	pid1, err := SpawnPrefixName(new(gsReg), "regGroup", "reused")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the name is taken over by another process
+	UnregisterPrefix("regGroup", "reused")
+
+	pid2, err := SpawnPrefixName(new(gsReg), "regGroup", "reused")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid2.Stop()
+
+	pid1.Stop()
+
+	if pid := WhereisPrefix("regGroup", "reused"); pid != pid2 {
+		t.Errorf("exited process must not evict name of pid #%d, got #%d",
+			pid2.Id(), pid.Id())
+	}
+}
+
+func TestRegisterStopped(t *testing.T) {
+	pid, err := Spawn(new(gsReg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid.Stop()
+
+	if err := Register("stopped", pid); !IsNoProcError(err) {
+		t.Errorf("register stopped process must fail with no_proc: %v", err)
+	}
+
+	if Whereis("stopped") != nil {
+		t.Error("stopped process must not be registered")
+	}
+}
+
+//
+// registers itself and spawns a child in Init
+//
+type gsEnv struct {
+	GenServerImpl
+	child *Pid
+}
+
+func (s *gsEnv) Init(args ...interface{}) Term {
+	if err := s.Register(args[0], s.Self()); err != nil {
+		return &GsInitStop{err}
+	}
+
+	child, err := s.Spawn(new(gsInfo))
+	if err != nil {
+		return &GsInitStop{err}
+	}
+	s.child = child
+
+	return GsInitOk
+}
+
+func TestGenServerEnv(t *testing.T) {
+	a := NewEnv()
+
+	s := new(gsEnv)
+	pid, err := a.Spawn(s, "env_self")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Env() != a {
+		t.Error("process must keep its environment")
+	}
+
+	if a.Whereis("env_self") != pid {
+		t.Error("name must be registered in the process environment")
+	}
+	if Whereis("env_self") != nil {
+		t.Error("name must not be registered in the default environment")
+	}
+	if s.Whereis("env_self") != pid {
+		t.Error("Whereis must use the process environment")
+	}
+
+	if a.Count() != 2 {
+		t.Errorf("child must be spawned in the process environment, got %d",
+			a.Count())
+	}
+
+	s.child.Stop()
+	pid.Stop()
+
+	if new(gsEnv).Env() != env {
+		t.Error("not spawned process must use the default environment")
+	}
+}
+
+func TestRegisterOtherEnv(t *testing.T) {
+	a := NewEnv()
+
+	pid, err := Spawn(new(gsInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Register("other_env", pid); err != nil {
+		t.Fatal(err)
+	}
+
+	pid.Stop()
+
+	if a.Whereis("other_env") != nil {
+		t.Error("name in other environment must be unregistered on exit")
+	}
+}
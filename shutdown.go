@@ -0,0 +1,61 @@
+package act
+
+import (
+	"context"
+	"errors"
+)
+
+//
+// ErrEnvShutdown is returned on spawn in the environment being shut down
+//
+var ErrEnvShutdown = errors.New("environment is shut down")
+
+//
+// Shutdown stops all processes of the environment in reverse spawn order
+// with Shutdown reason, children are stopped before their supervisor. New
+// processes can not be spawned after the call, registry watchers are closed.
+// If ctx is done before all processes are stopped, stop requests are sent to
+// the rest of processes without waiting and ctx.Err() is returned
+//
+func (a *Act) Shutdown(ctx context.Context) error {
+	a.procs.close()
+
+	pids := a.procs.list()
+
+	var err error
+
+	for i := len(pids) - 1; i >= 0; i-- {
+		if err != nil {
+			pids[i].stopAsync(Shutdown)
+			continue
+		}
+
+		if e := pids[i].stopContext(ctx, Shutdown); e != nil &&
+			!IsNoProcError(e) {
+			err = e
+		}
+	}
+
+	a.registry.watchers.closeAll()
+	a.nodes.closeAll()
+
+	return err
+}
+
+// ---------------------------------------------------------------------------
+//
+// kill makes the process exited for others: the mailbox is closed, names
+// and groups are removed. The process goroutine exits when the current
+// handler returns
+//
+func (pid *Pid) kill() {
+	pid.unregisterNames()
+	pid.leaveGroups()
+	pid.unregisterGlobals()
+	pid.closeMailbox(nil)
+}
+
+func (pid *Pid) stopAsync(reason error) {
+	pid.mbox.push(
+		context.Background(), prioritySystem, &stopReq{reason, make(chan bool, 1)})
+}
@@ -0,0 +1,171 @@
+package act
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+//
+// records Terminate reasons
+//
+type terminated struct {
+	mu    sync.Mutex
+	order []uint64
+}
+
+func (t *terminated) add(id uint64) {
+	t.mu.Lock()
+	t.order = append(t.order, id)
+	t.mu.Unlock()
+}
+
+type gsShutdown struct {
+	GenServerImpl
+	terminated *terminated
+	reason     error
+	block      chan struct{}
+}
+
+func (s *gsShutdown) HandleCall(req Term, from From) Term {
+	if req == "block" {
+		<-s.block
+	}
+
+	return GsCallReplyOk
+}
+
+func (s *gsShutdown) Terminate(reason error) {
+	s.reason = reason
+	s.terminated.add(s.Id())
+}
+
+func TestShutdown(t *testing.T) {
+	a := NewEnv()
+	term := new(terminated)
+
+	events, _ := a.WatchPrefix("")
+
+	servers := make([]*gsShutdown, 3)
+	for i := range servers {
+		servers[i] = &gsShutdown{terminated: term}
+		if _, err := a.Spawn(servers[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []uint64{servers[2].Id(), servers[1].Id(), servers[0].Id()}
+	if !equalIds(term.order, want) {
+		t.Errorf("want stop order %v, got %v", want, term.order)
+	}
+
+	for _, s := range servers {
+		if !errors.Is(s.reason, Shutdown) {
+			t.Errorf("want Shutdown reason, got %v", s.reason)
+		}
+	}
+
+	if n := a.Count(); n != 0 {
+		t.Errorf("want no processes, got %d", n)
+	}
+
+	if _, err := a.Spawn(new(gsInfo)); !errors.Is(err, ErrEnvShutdown) {
+		t.Errorf("want ErrEnvShutdown, got %v", err)
+	}
+
+	if _, ok := <-events; ok {
+		t.Error("watcher must be closed")
+	}
+
+	events, cancel := a.WatchPrefix("")
+	if _, ok := <-events; ok {
+		t.Error("watcher must be closed after shutdown")
+	}
+	cancel()
+}
+
+func TestShutdownSupervisor(t *testing.T) {
+	a := NewEnv()
+	term := new(terminated)
+
+	spec := &SupervisorSpec{
+		Strategy: OneForAll,
+		Children: []*ChildSpec{
+			{Id: "a", Start: func() GenServer {
+				return &gsShutdown{terminated: term}
+			}},
+			{Id: "b", Start: func() GenServer {
+				return &gsShutdown{terminated: term}
+			}},
+		},
+	}
+
+	sup, err := a.StartSupervisor(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	children, err := WhichChildren(sup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// children are not restarted
+	want := []uint64{children["b"].Id(), children["a"].Id()}
+	if !equalIds(term.order, want) {
+		t.Errorf("want stop order %v, got %v", want, term.order)
+	}
+
+	if sup.IsAlive() || a.Count() != 0 {
+		t.Errorf("all processes must be stopped, %d alive", a.Count())
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	a := NewEnv()
+
+	s := &gsShutdown{terminated: new(terminated), block: make(chan struct{})}
+	pid, err := a.Spawn(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go pid.Call("block")
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := a.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want DeadlineExceeded, got %v", err)
+	}
+
+	close(s.block)
+	time.Sleep(50 * time.Millisecond)
+
+	if pid.IsAlive() {
+		t.Error("process must be stopped after it is unblocked")
+	}
+}
+
+func equalIds(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,400 @@
+package act
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//
+// RestartType defines when a terminated child process must be restarted
+//
+type RestartType int
+
+const (
+	// Permanent child process is always restarted
+	Permanent RestartType = iota
+	// Transient child process is restarted only if it terminates abnormally,
+	// with reason other than Normal or Shutdown
+	Transient
+	// Temporary child process is never restarted
+	Temporary
+)
+
+//
+// Strategy defines how the supervisor restarts child processes
+//
+type Strategy int
+
+const (
+	// OneForOne restarts only the terminated child process
+	OneForOne Strategy = iota
+	// OneForAll restarts all child processes
+	OneForAll
+	// RestForOne restarts the terminated child process and all child
+	// processes started after it
+	RestForOne
+)
+
+const (
+	defaultRestartPeriod = 5 * time.Second
+	defaultShutdown      = 5 * time.Second
+	restartRetryMs       = 100 // delay to retry the failed child start
+)
+
+//
+// ErrMaxRestartIntensity is the reason the supervisor stops with when
+// the restart intensity is reached
+//
+var ErrMaxRestartIntensity = errors.New("reached max restart intensity")
+
+//
+// ChildSpec describes a child process of the supervisor
+//
+type ChildSpec struct {
+	Id      string           // Id of the child, unique within the supervisor
+	Start   func() GenServer // Start returns a new GenServer to spawn
+	Opts    *Opts            // Opts to spawn the child, may be nil
+	Args    []interface{}    // Args passed to the Init callback
+	Restart RestartType
+
+	// Shutdown is the time to wait for the child to stop, 5 seconds by
+	// default, negative to wait without limit. The child not stopped in time
+	// is killed: its mailbox is closed, names and groups are removed, it
+	// exits when the current handler returns
+	Shutdown time.Duration
+}
+
+//
+// SupervisorSpec describes the supervisor behaviour. The supervisor stops
+// itself and all child processes if more than MaxRestarts restarts occur
+// within Period. MaxRestarts 0 allows no restarts, Period is 5 seconds
+// by default
+//
+type SupervisorSpec struct {
+	Strategy    Strategy
+	MaxRestarts int
+	Period      time.Duration
+	Children    []*ChildSpec
+}
+
+type child struct {
+	spec *ChildSpec
+	pid  *Pid
+	ref  MonitorRef
+}
+
+type whichChildrenReq struct{}
+
+// restartChildReq retries the failed start of the child
+type restartChildReq struct {
+	c *child
+}
+
+type supervisor struct {
+	GenServerImpl
+	env      *Act
+	spec     SupervisorSpec // copy of the spec with defaults
+	children []*child
+	restarts []time.Time
+}
+
+//
+// StartSupervisor spawns a new supervisor process with given spec and opts
+//
+func StartSupervisor(spec *SupervisorSpec, opts *Opts) (*Pid, error) {
+	return env.StartSupervisor(spec, opts)
+}
+
+func (a *Act) StartSupervisor(spec *SupervisorSpec, opts *Opts) (*Pid, error) {
+	if opts == nil {
+		opts = &Opts{}
+	}
+
+	s := &supervisor{
+		env: a,
+	}
+	if spec != nil {
+		s.spec = *spec
+		s.spec.Children = append([]*ChildSpec(nil), spec.Children...)
+	}
+	if s.spec.Period <= 0 {
+		s.spec.Period = defaultRestartPeriod
+	}
+
+	return a.SpawnOpts(s, opts)
+}
+
+//
+// WhichChildren returns running child processes of the supervisor by id
+//
+func WhichChildren(sup *Pid) (map[string]*Pid, error) {
+	r, err := sup.Call(whichChildrenReq{})
+	if err != nil {
+		return nil, err
+	}
+
+	children, ok := r.(map[string]*Pid)
+	if !ok {
+		return nil, fmt.Errorf("pid #%d is not a supervisor", sup.Id())
+	}
+
+	return children, nil
+}
+
+// ---------------------------------------------------------------------------
+// GenServer callbacks
+// ---------------------------------------------------------------------------
+func (s *supervisor) Init(args ...interface{}) Term {
+
+	ids := make(map[string]bool)
+
+	for _, spec := range s.spec.Children {
+		if ids[spec.Id] {
+			s.stopChildren(s.children)
+			return &GsInitStop{
+				fmt.Errorf("duplicate child id '%s'", spec.Id)}
+		}
+		ids[spec.Id] = true
+
+		c := &child{spec: spec}
+		s.children = append(s.children, c)
+
+		if err := s.startChild(c); err != nil {
+			s.stopChildren(s.children)
+			return &GsInitStop{
+				fmt.Errorf("start child '%s' failed: %w", spec.Id, err)}
+		}
+	}
+
+	return GsInitOk
+}
+
+func (s *supervisor) HandleCall(req Term, from From) Term {
+
+	switch req.(type) {
+	case whichChildrenReq:
+		children := make(map[string]*Pid)
+		for _, c := range s.children {
+			if c.pid != nil {
+				children[c.spec.Id] = c.pid
+			}
+		}
+
+		return &GsCallReply{children}
+	}
+
+	return fmt.Errorf("unexpected call: %#v", req)
+}
+
+func (s *supervisor) HandleCast(req Term) Term {
+
+	switch req := req.(type) {
+	case Down:
+		i := s.childByRef(req.Ref)
+		if i < 0 {
+			return GsCastNoReply
+		}
+
+		c := s.children[i]
+		c.pid = nil
+
+		// children are stopped by the environment shutdown
+		if s.env.procs.isClosed() ||
+			!needRestart(c.spec.Restart, req.Reason) {
+
+			if c.spec.Restart == Temporary {
+				s.children = append(s.children[:i], s.children[i+1:]...)
+			}
+			return GsCastNoReply
+		}
+
+		if !s.restart(i) {
+			return &GsCastStop{ErrMaxRestartIntensity}
+		}
+
+	case restartChildReq:
+		if req.c.pid != nil || !s.hasChild(req.c) {
+			return GsCastNoReply
+		}
+
+		if !s.addRestart() {
+			return &GsCastStop{ErrMaxRestartIntensity}
+		}
+		s.tryStart(req.c)
+	}
+
+	return GsCastNoReply
+}
+
+func (s *supervisor) Terminate(reason error) {
+	s.stopChildren(s.children)
+}
+
+// ---------------------------------------------------------------------------
+func needRestart(restart RestartType, reason error) bool {
+	switch restart {
+	case Permanent:
+		return true
+	case Transient:
+		return isAbnormal(reason)
+	}
+
+	return false
+}
+
+func (s *supervisor) childByRef(ref MonitorRef) int {
+	for i, c := range s.children {
+		if c.pid != nil && c.ref == ref {
+			return i
+		}
+	}
+
+	return -1
+}
+
+//
+// restart restarts children according to the strategy after the child i
+// terminated. Returns false if the restart intensity is reached
+//
+func (s *supervisor) restart(i int) bool {
+
+	var group []*child
+
+	switch s.spec.Strategy {
+	case OneForAll:
+		group = s.children
+	case RestForOne:
+		group = s.children[i:]
+	default:
+		group = s.children[i : i+1]
+	}
+
+	s.stopChildren(group)
+
+	// temporary children are not restarted
+	restart := make([]*child, 0, len(group))
+	for _, c := range group {
+		if c.spec.Restart != Temporary {
+			restart = append(restart, c)
+		}
+	}
+
+	children := make([]*child, 0, len(s.children))
+	for _, c := range s.children {
+		if c.spec.Restart != Temporary || c.pid != nil {
+			children = append(children, c)
+		}
+	}
+	s.children = children
+
+	if !s.addRestart() {
+		return false
+	}
+
+	for _, c := range restart {
+		s.tryStart(c)
+	}
+
+	return true
+}
+
+//
+// tryStart starts the child, the failed start is retried later and counts
+// as a restart
+//
+func (s *supervisor) tryStart(c *child) {
+	err := s.startChild(c)
+	if err == nil {
+		return
+	}
+
+	s.Self().log().Warn("restart child failed",
+		s.Self().logFields("child", c.spec.Id, "error", err)...)
+
+	s.Self().SendAfter(restartChildReq{c}, restartRetryMs)
+}
+
+func (s *supervisor) hasChild(c *child) bool {
+	for _, child := range s.children {
+		if child == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *supervisor) addRestart() bool {
+	now := time.Now()
+
+	restarts := s.restarts[:0]
+	for _, t := range s.restarts {
+		if now.Sub(t) < s.spec.Period {
+			restarts = append(restarts, t)
+		}
+	}
+	s.restarts = append(restarts, now)
+
+	return len(s.restarts) <= s.spec.MaxRestarts
+}
+
+func (s *supervisor) startChild(c *child) error {
+
+	opts := &Opts{}
+	if c.spec.Opts != nil {
+		o := *c.spec.Opts
+		opts = &o
+	}
+
+	pid, err := s.env.SpawnOpts(c.spec.Start(), opts, c.spec.Args...)
+	if err != nil {
+		return err
+	}
+
+	c.pid = pid
+	c.ref = s.Monitor(pid)
+
+	return nil
+}
+
+//
+// stopChildren stops running children in reverse start order
+//
+func (s *supervisor) stopChildren(children []*child) {
+	for i := len(children) - 1; i >= 0; i-- {
+		c := children[i]
+		if c.pid == nil {
+			continue
+		}
+
+		s.Demonitor(c.ref)
+		s.stopChild(c)
+		c.pid = nil
+	}
+}
+
+//
+// stopChild stops the child and waits at most the shutdown time of the child
+//
+func (s *supervisor) stopChild(c *child) {
+	ctx := context.Background()
+
+	if shutdown := c.spec.Shutdown; shutdown >= 0 {
+		if shutdown == 0 {
+			shutdown = defaultShutdown
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, shutdown)
+		defer cancel()
+	}
+
+	err := c.pid.stopContext(ctx, Shutdown)
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.Self().log().Warn("child shutdown timeout, killed",
+			s.Self().logFields("child", c.spec.Id)...)
+		c.pid.kill()
+	}
+}
@@ -0,0 +1,353 @@
+package act
+
+import (
+	"testing"
+	"time"
+)
+
+func newGs() GenServer {
+	return new(gs)
+}
+
+func supSpec(strategy Strategy, restart RestartType, ids ...string) *SupervisorSpec {
+	spec := &SupervisorSpec{Strategy: strategy, MaxRestarts: 3}
+
+	for _, id := range ids {
+		spec.Children = append(spec.Children, &ChildSpec{
+			Id:      id,
+			Start:   newGs,
+			Opts:    &Opts{Prefix: "supGroup", Name: id},
+			Restart: restart,
+		})
+	}
+
+	return spec
+}
+
+func whichChildren(t *testing.T, sup *Pid) map[string]*Pid {
+	children, err := WhichChildren(sup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return children
+}
+
+// waitRestart waits for the child id is restarted with a pid other than old
+func waitRestart(t *testing.T, sup *Pid, id string, old *Pid) *Pid {
+	for i := 0; i < 100; i++ {
+		if pid := whichChildren(t, sup)[id]; pid != nil && pid != old {
+			return pid
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("child '%s' not restarted", id)
+	return nil
+}
+
+func TestSupervisorOneForOne(t *testing.T) {
+	sup, err := StartSupervisor(
+		supSpec(OneForOne, Permanent, "ofo1", "ofo2"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Stop()
+
+	children := whichChildren(t, sup)
+	if len(children) != 2 {
+		t.Fatalf("expected 2 children, got %d", len(children))
+	}
+
+	old1 := children["ofo1"]
+	old1.Call(cmdCrash)
+
+	pid1 := waitRestart(t, sup, "ofo1", old1)
+
+	if WhereisPrefix("supGroup", "ofo1") != pid1 {
+		t.Error("restarted child must be registered with the same name")
+	}
+
+	if whichChildren(t, sup)["ofo2"] != children["ofo2"] {
+		t.Error("other child must not be restarted")
+	}
+
+	if _, err := inc(pid1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSupervisorOneForAll(t *testing.T) {
+	sup, err := StartSupervisor(
+		supSpec(OneForAll, Permanent, "ofa1", "ofa2", "ofa3"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Stop()
+
+	children := whichChildren(t, sup)
+	children["ofa2"].Stop()
+
+	for _, id := range []string{"ofa1", "ofa2", "ofa3"} {
+		waitRestart(t, sup, id, children[id])
+	}
+}
+
+func TestSupervisorRestForOne(t *testing.T) {
+	sup, err := StartSupervisor(
+		supSpec(RestForOne, Permanent, "rfo1", "rfo2", "rfo3"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Stop()
+
+	children := whichChildren(t, sup)
+	children["rfo2"].Stop()
+
+	waitRestart(t, sup, "rfo2", children["rfo2"])
+	waitRestart(t, sup, "rfo3", children["rfo3"])
+
+	if whichChildren(t, sup)["rfo1"] != children["rfo1"] {
+		t.Error("child started before must not be restarted")
+	}
+}
+
+func TestSupervisorRestartType(t *testing.T) {
+	spec := supSpec(OneForOne, Temporary, "temp")
+	spec.Children = append(spec.Children,
+		supSpec(OneForOne, Transient, "trans").Children...)
+
+	sup, err := StartSupervisor(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Stop()
+
+	children := whichChildren(t, sup)
+
+	children["temp"].Call(cmdCrash)
+	children["trans"].StopReason(Normal)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := len(whichChildren(t, sup)); n != 0 {
+		t.Errorf("children must not be restarted, got %d running", n)
+	}
+}
+
+func TestSupervisorIntensity(t *testing.T) {
+	spec := supSpec(OneForOne, Permanent, "intensity")
+	spec.MaxRestarts = 2
+	spec.Period = time.Second
+
+	sup, err := StartSupervisor(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var old *Pid
+	for i := 0; i < 3; i++ {
+		pid := waitRestart(t, sup, "intensity", old)
+		pid.Call(cmdCrash)
+		old = pid
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := sup.Call(whichChildrenReq{}); err == nil {
+		t.Error("supervisor must be stopped")
+	}
+
+	if WhereisPrefix("supGroup", "intensity") != nil {
+		t.Error("child must be stopped with supervisor")
+	}
+}
+
+func TestSupervisorStop(t *testing.T) {
+	sup, err := StartSupervisor(
+		supSpec(OneForOne, Permanent, "stop1", "stop2"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	children := whichChildren(t, sup)
+
+	if err := sup.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, pid := range children {
+		if _, err := pid.Call(cmdTest); err == nil {
+			t.Errorf("child '%s' must be stopped", id)
+		}
+	}
+}
+
+func TestSupervisorInitFail(t *testing.T) {
+	spec := supSpec(OneForOne, Permanent, "fail1")
+	spec.Children = append(spec.Children, &ChildSpec{
+		Id:    "fail2",
+		Start: newGs,
+		Args:  []interface{}{true}, // Init returns GsInitStop
+	})
+
+	_, err := StartSupervisor(spec, nil)
+	if err == nil {
+		t.Fatal("supervisor must not be started")
+	}
+
+	if WhereisPrefix("supGroup", "fail1") != nil {
+		t.Error("started children must be stopped")
+	}
+}
+
+func TestSupervisorNoRestarts(t *testing.T) {
+	spec := supSpec(OneForOne, Permanent, "norestart")
+	spec.MaxRestarts = 0
+
+	sup, err := StartSupervisor(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Period != 0 {
+		t.Errorf("spec must not be changed, got period %v", spec.Period)
+	}
+
+	whichChildren(t, sup)["norestart"].Call(cmdCrash)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := sup.Call(whichChildrenReq{}); err == nil {
+		t.Error("supervisor must be stopped")
+	}
+}
+
+//
+// gsSlowStop takes long time to terminate
+//
+type gsSlowStop struct {
+	GenServerImpl
+}
+
+func (s *gsSlowStop) Terminate(reason error) {
+	time.Sleep(time.Second)
+}
+
+func TestSupervisorChildShutdown(t *testing.T) {
+	spec := &SupervisorSpec{
+		Children: []*ChildSpec{{
+			Id:       "slow",
+			Start:    func() GenServer { return new(gsSlowStop) },
+			Shutdown: 50 * time.Millisecond,
+		}},
+	}
+
+	sup, err := StartSupervisor(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	child := whichChildren(t, sup)["slow"]
+
+	start := time.Now()
+	if err := sup.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("supervisor must not wait for the child, stopped in %v", d)
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+
+	if _, err := child.Call(cmdTest); err == nil {
+		t.Error("child must be stopped")
+	}
+}
+
+func TestSupervisorKillChild(t *testing.T) {
+	spec := &SupervisorSpec{
+		Strategy:    OneForAll,
+		MaxRestarts: 3,
+		Children: []*ChildSpec{{
+			Id:       "slowKill",
+			Start:    func() GenServer { return new(gsSlowStop) },
+			Opts:     &Opts{Prefix: "supGroup", Name: "slowKill"},
+			Shutdown: 50 * time.Millisecond,
+		}, {
+			Id:    "crashKill",
+			Start: newGs,
+		}},
+	}
+
+	sup, err := StartSupervisor(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Stop()
+
+	children := whichChildren(t, sup)
+	slow := children["slowKill"]
+
+	children["crashKill"].Call(cmdCrash)
+
+	restarted := waitRestart(t, sup, "slowKill", slow)
+
+	if slow.IsAlive() {
+		t.Error("child not stopped in time must be killed")
+	}
+	if pid := WhereisPrefix("supGroup", "slowKill"); pid != restarted {
+		t.Errorf("name must be registered for the restarted child, got %v", pid)
+	}
+}
+
+//
+// gsRestartFail fails Init after the first start
+//
+type gsRestartFail struct {
+	GenServerImpl
+	fail bool
+}
+
+func (s *gsRestartFail) Init(args ...interface{}) Term {
+	if s.fail {
+		return &GsInitStop{errStop}
+	}
+
+	return GsInitOk
+}
+
+func TestSupervisorStartFail(t *testing.T) {
+	started := false
+	spec := &SupervisorSpec{
+		MaxRestarts: 3,
+		Children: []*ChildSpec{{
+			Id: "initFail",
+			Start: func() GenServer {
+				s := &gsRestartFail{fail: started}
+				started = true
+				return s
+			},
+		}},
+	}
+
+	sup, err := StartSupervisor(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Stop()
+
+	whichChildren(t, sup)["initFail"].StopReason(errStop)
+
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := sup.Call(whichChildrenReq{}); err != nil {
+		t.Fatal("failed start must be retried later", err)
+	}
+
+	time.Sleep(time.Second)
+
+	if _, err := sup.Call(whichChildrenReq{}); err == nil {
+		t.Error("supervisor must be stopped by failed restarts")
+	}
+}
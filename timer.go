@@ -23,6 +23,18 @@ func (pid *Pid) SendAfterWithStop(data Term, timeoutMs uint32) *Timer {
 	return &Timer{timer: timer}
 }
 
+//
+// timeoutAfter returns timer sending GsTimeout with system priority
+//
+func (pid *Pid) timeoutAfter(timeoutMs uint32) *Timer {
+
+	d := time.Duration(timeoutMs) * time.Millisecond
+	timer := time.AfterFunc(d, func() { pid.castSystem(gsTimeout) })
+	pid.timeoutAt.Store(time.Now().Add(d).UnixNano())
+
+	return &Timer{timer: timer}
+}
+
 //
 // Stop stops the timer
 //
@@ -0,0 +1,298 @@
+package act
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+//
+// TypedGenServer is the GenServer with typed requests and replies.
+// Requests of type Req are passed to HandleTypedCall and HandleTypedCast,
+// other cast messages (timeouts, Down, Exit) are passed to HandleCast.
+// HandleTypedCall returns results made by TypedFrom, so replies are of
+// type Resp
+//
+type TypedGenServer[Req, Resp any] interface {
+	GenServer
+	HandleTypedCall(req Req, from TypedFrom[Resp]) TypedCallResult[Resp]
+	HandleTypedCast(req Req) (result Term)
+}
+
+//
+// TypedFrom is the typed From to reply to the caller with Reply() and to
+// make results of HandleTypedCall
+//
+type TypedFrom[Resp any] struct {
+	from From
+}
+
+//
+// TypedCallResult is the result of HandleTypedCall. The zero result replies
+// with the zero Resp
+//
+type TypedCallResult[Resp any] struct {
+	result Term
+}
+
+//
+// Reply sends typed reply to caller
+//
+func (from TypedFrom[Resp]) Reply(reply Resp) {
+	Reply(from.from, typedReply[Resp]{reply})
+}
+
+//
+// CallReply returns the result to reply to the caller, as GsCallReply
+//
+func (from TypedFrom[Resp]) CallReply(reply Resp) TypedCallResult[Resp] {
+	return TypedCallResult[Resp]{&GsCallReply{typedReply[Resp]{reply}}}
+}
+
+//
+// CallReplyTimeout returns the result to reply to the caller and to set
+// an inactivity timer, as GsCallReplyTimeout
+//
+func (from TypedFrom[Resp]) CallReplyTimeout(
+	reply Resp,
+	timeout uint32) TypedCallResult[Resp] {
+
+	return TypedCallResult[Resp]{
+		&GsCallReplyTimeout{Reply: typedReply[Resp]{reply}, Timeout: timeout}}
+}
+
+//
+// NoReply returns the result to reply later with Reply(), as GsCallNoReply
+//
+func (from TypedFrom[Resp]) NoReply() TypedCallResult[Resp] {
+	return TypedCallResult[Resp]{GsCallNoReply}
+}
+
+//
+// NoReplyTimeout returns the result to reply later with Reply() and to set
+// an inactivity timer, as GsCallNoReplyTimeout
+//
+func (from TypedFrom[Resp]) NoReplyTimeout(
+	timeout uint32) TypedCallResult[Resp] {
+
+	return TypedCallResult[Resp]{&GsCallNoReplyTimeout{timeout}}
+}
+
+//
+// Defer returns the result to defer the request, as GsCallDefer
+//
+func (from TypedFrom[Resp]) Defer() TypedCallResult[Resp] {
+	return TypedCallResult[Resp]{GsCallDefer}
+}
+
+//
+// Stop returns the result to reply to the caller and to stop the process
+// with the reason, as GsCallStop
+//
+func (from TypedFrom[Resp]) Stop(
+	reason error,
+	reply Resp) TypedCallResult[Resp] {
+
+	return TypedCallResult[Resp]{
+		&GsCallStop{Reason: reason, Reply: typedReply[Resp]{reply}}}
+}
+
+//
+// Error returns the result to return the error to the caller
+//
+func (from TypedFrom[Resp]) Error(err error) TypedCallResult[Resp] {
+	return TypedCallResult[Resp]{err}
+}
+
+//
+// TypedPid is the pid of TypedGenServer process
+//
+type TypedPid[Req, Resp any] struct {
+	pid *Pid
+}
+
+// typedReply wraps reply so nil values pass through Call
+type typedReply[Resp any] struct {
+	reply Resp
+}
+
+//
+// typedTerm is the typed reply of any type, replies to other nodes are
+// sent unwrapped
+//
+type typedTerm interface {
+	term() Term
+}
+
+func (r typedReply[Resp]) term() Term {
+	return r.reply
+}
+
+//
+// typedServer adapts TypedGenServer to GenServer
+//
+type typedServer[Req, Resp any] struct {
+	TypedGenServer[Req, Resp]
+}
+
+//
+// SpawnTyped spawns a new TypedGenServer process with given opts
+//
+func SpawnTyped[S TypedGenServer[Req, Resp], Req, Resp any](
+	gs S,
+	opts *Opts,
+	args ...interface{}) (TypedPid[Req, Resp], error) {
+
+	return SpawnTypedEnv[S, Req, Resp](env, gs, opts, args...)
+}
+
+//
+// SpawnTypedEnv spawns a new TypedGenServer process in the environment
+//
+func SpawnTypedEnv[S TypedGenServer[Req, Resp], Req, Resp any](
+	a *Act,
+	gs S,
+	opts *Opts,
+	args ...interface{}) (TypedPid[Req, Resp], error) {
+
+	if opts == nil {
+		opts = &Opts{}
+	}
+
+	pid, err := a.SpawnOpts(&typedServer[Req, Resp]{gs}, opts, args...)
+
+	return TypedPid[Req, Resp]{pid}, err
+}
+
+//
+// NewTypedPid returns typed pid of TypedGenServer process, for example
+// found by Whereis
+//
+func NewTypedPid[Req, Resp any](pid *Pid) TypedPid[Req, Resp] {
+	return TypedPid[Req, Resp]{pid}
+}
+
+//
+// Pid returns untyped pid of the process
+//
+func (p TypedPid[Req, Resp]) Pid() *Pid {
+	return p.pid
+}
+
+//
+// Id returns process identificator
+//
+func (p TypedPid[Req, Resp]) Id() uint64 {
+	return p.pid.Id()
+}
+
+//
+// Call makes a synchronous call to the process
+//
+func (p TypedPid[Req, Resp]) Call(req Req) (Resp, error) {
+	return p.CallContext(context.Background(), req)
+}
+
+//
+// CallTimeout makes a synchronous call to the process and waits for reply at
+// most timeout
+//
+func (p TypedPid[Req, Resp]) CallTimeout(
+	req Req,
+	timeout time.Duration) (Resp, error) {
+
+	return typedResult[Resp](p.pid.CallTimeout(req, timeout))
+}
+
+//
+// CallContext makes a synchronous call to the process with context
+//
+func (p TypedPid[Req, Resp]) CallContext(
+	ctx context.Context,
+	req Req) (Resp, error) {
+
+	return typedResult[Resp](p.pid.CallContext(ctx, req))
+}
+
+//
+// Cast makes an asynchronous call to the process
+//
+func (p TypedPid[Req, Resp]) Cast(req Req) error {
+	return p.pid.Cast(req)
+}
+
+//
+// TryCast makes an asynchronous call to the process without waiting
+//
+func (p TypedPid[Req, Resp]) TryCast(req Req) error {
+	return p.pid.TryCast(req)
+}
+
+//
+// CastTimeout makes an asynchronous call to the process, waits at most
+// timeout for room in the process mailbox
+//
+func (p TypedPid[Req, Resp]) CastTimeout(req Req, timeout time.Duration) error {
+	return p.pid.CastTimeout(req, timeout)
+}
+
+//
+// CastContext makes an asynchronous call to the process with context
+//
+func (p TypedPid[Req, Resp]) CastContext(ctx context.Context, req Req) error {
+	return p.pid.CastContext(ctx, req)
+}
+
+//
+// Stop makes synchronous stop request to the process
+//
+func (p TypedPid[Req, Resp]) Stop() error {
+	return p.pid.Stop()
+}
+
+func typedResult[Resp any](r Term, err error) (Resp, error) {
+	var zero Resp
+
+	if err != nil {
+		return zero, err
+	}
+
+	switch r := r.(type) {
+	case typedReply[Resp]:
+		return r.reply, nil
+	case Resp:
+		// unwrapped reply of the remote process
+		return r, nil
+	case nil:
+		return zero, nil
+	}
+
+	return zero, fmt.Errorf("unexpected reply: %#v", r)
+}
+
+// ---------------------------------------------------------------------------
+// GenServer callbacks
+// ---------------------------------------------------------------------------
+func (s *typedServer[Req, Resp]) HandleCall(req Term, from From) Term {
+
+	r, ok := req.(Req)
+	if !ok {
+		return fmt.Errorf("unexpected call: %#v", req)
+	}
+
+	result := s.HandleTypedCall(r, TypedFrom[Resp]{from}).result
+	if result == nil {
+		return &GsCallReply{typedReply[Resp]{}}
+	}
+
+	return result
+}
+
+func (s *typedServer[Req, Resp]) HandleCast(req Term) Term {
+
+	if r, ok := req.(Req); ok {
+		return s.HandleTypedCast(r)
+	}
+
+	return s.TypedGenServer.HandleCast(req)
+}
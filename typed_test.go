@@ -0,0 +1,209 @@
+package act
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+//
+// typed counter
+//
+type counterReq struct {
+	op string
+	n  int
+}
+
+type counterResp struct {
+	value int
+}
+
+type gsCounter struct {
+	GenServerImpl
+	value    int
+	timeouts int
+}
+
+func (s *gsCounter) HandleTypedCall(
+	req counterReq,
+	from TypedFrom[*counterResp]) TypedCallResult[*counterResp] {
+
+	switch req.op {
+	case "get":
+		return from.CallReply(&counterResp{s.value})
+	case "nil":
+		return from.CallReply(nil)
+	case "ok":
+		return TypedCallResult[*counterResp]{}
+	case "later":
+		go from.Reply(&counterResp{s.value})
+		return from.NoReply()
+	case "timeouts":
+		return from.CallReply(&counterResp{s.timeouts})
+	case "stop":
+		return from.Stop(Normal, &counterResp{s.value})
+	}
+
+	return from.Error(errors.New("unknown op"))
+}
+
+func (s *gsCounter) HandleTypedCast(req counterReq) Term {
+	if req.op == "add" {
+		s.value += req.n
+	}
+
+	if req.op == "timeout" {
+		return &GsCastNoReplyTimeout{10}
+	}
+
+	return GsCastNoReply
+}
+
+func (s *gsCounter) HandleCast(req Term) Term {
+	if _, ok := req.(GsTimeout); ok {
+		s.timeouts++
+	}
+
+	return GsCastNoReply
+}
+
+func TestTypedPid(t *testing.T) {
+	pid, err := SpawnTyped(new(gsCounter), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	if err := pid.Cast(counterReq{op: "add", n: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := pid.Call(counterReq{op: "get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.value != 5 {
+		t.Errorf("want 5, got %d", r.value)
+	}
+
+	r, err = pid.Call(counterReq{op: "later"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.value != 5 {
+		t.Errorf("want 5 from Reply, got %d", r.value)
+	}
+
+	r, err = pid.Call(counterReq{op: "nil"})
+	if err != nil || r != nil {
+		t.Errorf("want nil reply, got %#v, %v", r, err)
+	}
+
+	r, err = pid.Call(counterReq{op: "ok"})
+	if err != nil || r != nil {
+		t.Errorf("want zero reply, got %#v, %v", r, err)
+	}
+
+	_, err = pid.Call(counterReq{op: "unknown"})
+	if err == nil || err.Error() != "unknown op" {
+		t.Errorf("want unknown op error, got %v", err)
+	}
+
+	r, err = pid.Call(counterReq{op: "stop"})
+	if err != nil || r.value != 5 {
+		t.Errorf("want 5 on stop, got %#v, %v", r, err)
+	}
+	for i := 0; i < 100 && pid.Pid().IsAlive(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pid.Pid().IsAlive() {
+		t.Error("process must be stopped")
+	}
+}
+
+func TestTypedPidUntyped(t *testing.T) {
+	pid, err := SpawnTyped(new(gsCounter), &Opts{Name: "typed_counter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pid.Stop()
+
+	// untyped request
+	if _, err := pid.Pid().Call("get"); err == nil {
+		t.Error("untyped call must fail")
+	}
+
+	// non request messages go to HandleCast
+	if err := pid.Cast(counterReq{op: "timeout"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	typed := NewTypedPid[counterReq, *counterResp](Whereis("typed_counter"))
+
+	r, err := typed.Call(counterReq{op: "timeouts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.value != 1 {
+		t.Errorf("want 1 timeout, got %d", r.value)
+	}
+}
+
+//
+// typed server over the node connection
+//
+type upperResp struct {
+	Text string
+}
+
+type gsUpper struct {
+	GenServerImpl
+}
+
+func init() {
+	RegisterType("act.upperResp", new(upperResp))
+}
+
+func (s *gsUpper) HandleTypedCall(
+	req string,
+	from TypedFrom[*upperResp]) TypedCallResult[*upperResp] {
+
+	if req == "nil" {
+		return from.CallReply(nil)
+	}
+
+	return from.CallReply(&upperResp{strings.ToUpper(req)})
+}
+
+func (s *gsUpper) HandleTypedCast(req string) Term {
+	return GsCastNoReply
+}
+
+func TestTypedPidRemote(t *testing.T) {
+	a, _, node, _ := startNodes(t, "127.0.0.1:0")
+
+	_, err := SpawnTypedEnv[*gsUpper, string, *upperResp](
+		a, new(gsUpper), &Opts{Name: "upper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := node.Whereis("upper")
+	if err != nil || remote == nil {
+		t.Fatal("want remote pid", err)
+	}
+
+	pid := NewTypedPid[string, *upperResp](remote)
+
+	r, err := pid.Call("hi")
+	if err != nil || r == nil || r.Text != "HI" {
+		t.Errorf("want HI, got %#v, %v", r, err)
+	}
+
+	r, err = pid.Call("nil")
+	if err != nil || r != nil {
+		t.Errorf("want nil reply, got %#v, %v", r, err)
+	}
+}
@@ -0,0 +1,190 @@
+package act
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+//
+// RegEventType is the type of registry event
+//
+type RegEventType int
+
+const (
+	// Registered is sent when the name is registered
+	Registered RegEventType = iota + 1
+	// Unregistered is sent when the name is unregistered or the process
+	// owning the name exited
+	Unregistered
+)
+
+//
+// RegEvent describes the change of the process registry
+//
+type RegEvent struct {
+	Type   RegEventType
+	Prefix string
+	Name   interface{}
+	Pid    *Pid
+}
+
+//
+// regWatcher delivers events to the channel in order. Events are queued, so
+// the slow reader never blocks the registry
+//
+type regWatcher struct {
+	mu     sync.Mutex
+	queue  []RegEvent
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	out    chan RegEvent
+}
+
+type regWatchers struct {
+	count    int32
+	mu       sync.RWMutex
+	byPrefix map[string]map[*regWatcher]struct{}
+	closed   bool // environment is shut down
+}
+
+//
+// WatchPrefix returns the channel of registry events for names with prefix.
+// Only changes made after the call are reported, use Whereare to get names
+// registered before. The channel is closed after cancel is called
+//
+func WatchPrefix(prefix string) (<-chan RegEvent, func()) {
+	return env.WatchPrefix(prefix)
+}
+
+func (a *Act) WatchPrefix(prefix string) (<-chan RegEvent, func()) {
+	return a.registry.watchers.add(prefix)
+}
+
+// ---------------------------------------------------------------------------
+func (ws *regWatchers) add(prefix string) (<-chan RegEvent, func()) {
+	w := &regWatcher{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		out:    make(chan RegEvent),
+	}
+
+	ws.mu.Lock()
+	if ws.closed {
+		ws.mu.Unlock()
+		close(w.out)
+		return w.out, func() {}
+	}
+	if ws.byPrefix == nil {
+		ws.byPrefix = make(map[string]map[*regWatcher]struct{})
+	}
+	if ws.byPrefix[prefix] == nil {
+		ws.byPrefix[prefix] = make(map[*regWatcher]struct{})
+	}
+	ws.byPrefix[prefix][w] = struct{}{}
+	atomic.AddInt32(&ws.count, 1)
+	ws.mu.Unlock()
+
+	go w.run()
+
+	cancel := func() {
+		ws.remove(prefix, w)
+		w.stop()
+	}
+
+	return w.out, cancel
+}
+
+//
+// closeAll stops all watchers, their channels are closed
+//
+func (ws *regWatchers) closeAll() {
+	ws.mu.Lock()
+	byPrefix := ws.byPrefix
+	ws.byPrefix = nil
+	ws.closed = true
+	atomic.StoreInt32(&ws.count, 0)
+	ws.mu.Unlock()
+
+	for _, watchers := range byPrefix {
+		for w := range watchers {
+			w.stop()
+		}
+	}
+}
+
+func (ws *regWatchers) remove(prefix string, w *regWatcher) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if _, ok := ws.byPrefix[prefix][w]; !ok {
+		return
+	}
+
+	delete(ws.byPrefix[prefix], w)
+	if len(ws.byPrefix[prefix]) == 0 {
+		delete(ws.byPrefix, prefix)
+	}
+	atomic.AddInt32(&ws.count, -1)
+}
+
+func (ws *regWatchers) notify(
+	t RegEventType,
+	prefix string,
+	name interface{},
+	pid *Pid) {
+
+	if atomic.LoadInt32(&ws.count) == 0 {
+		return
+	}
+
+	e := RegEvent{Type: t, Prefix: prefix, Name: name, Pid: pid}
+
+	ws.mu.RLock()
+	for w := range ws.byPrefix[prefix] {
+		w.push(e)
+	}
+	ws.mu.RUnlock()
+}
+
+func (w *regWatcher) push(e RegEvent) {
+	w.mu.Lock()
+	w.queue = append(w.queue, e)
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *regWatcher) stop() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
+
+func (w *regWatcher) run() {
+	defer close(w.out)
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.notify:
+		}
+
+		w.mu.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, e := range queue {
+			select {
+			case w.out <- e:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
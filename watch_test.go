@@ -0,0 +1,95 @@
+package act
+
+import (
+	"testing"
+	"time"
+)
+
+func waitRegEvent(t *testing.T, events <-chan RegEvent) RegEvent {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no registry event received")
+	}
+
+	return RegEvent{}
+}
+
+func TestWatchPrefix(t *testing.T) {
+	env := NewEnv()
+
+	events, cancel := env.WatchPrefix("workers")
+	defer cancel()
+
+	pid, err := env.startServerOpts(&Opts{Prefix: "workers", Name: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// other prefix is not reported
+	other, err := env.startServerOpts(&Opts{Prefix: "other", Name: "w1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Stop()
+
+	e := waitRegEvent(t, events)
+	if e.Type != Registered || e.Prefix != "workers" || e.Name != "w1" ||
+		e.Pid != pid {
+		t.Errorf("unexpected event: %#v", e)
+	}
+
+	if err := env.RegisterPrefix("workers", "w2", pid); err != nil {
+		t.Fatal(err)
+	}
+
+	e = waitRegEvent(t, events)
+	if e.Type != Registered || e.Name != "w2" {
+		t.Errorf("unexpected event: %#v", e)
+	}
+
+	env.UnregisterPrefix("workers", "w2")
+
+	e = waitRegEvent(t, events)
+	if e.Type != Unregistered || e.Name != "w2" {
+		t.Errorf("unexpected event: %#v", e)
+	}
+
+	pid.Stop()
+
+	e = waitRegEvent(t, events)
+	if e.Type != Unregistered || e.Name != "w1" || e.Pid != pid {
+		t.Errorf("unexpected event: %#v", e)
+	}
+}
+
+func TestWatchPrefixCancel(t *testing.T) {
+	env := NewEnv()
+
+	events, cancel := env.WatchPrefix("workers")
+
+	// events are queued while nobody reads the channel
+	for i := 0; i < 10; i++ {
+		pid, err := env.startServerOpts(&Opts{Prefix: "workers", Name: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pid.Stop()
+	}
+
+	cancel()
+	cancel()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel must be closed after cancel")
+		}
+	}
+}
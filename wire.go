@@ -0,0 +1,324 @@
+package act
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+//
+// frameType is the type of frame sent between nodes
+//
+type frameType int
+
+const (
+	frameHello frameType = iota + 1
+	frameCall
+	frameCast
+	frameStop
+	frameWhereis
+	frameReply
+	frameHeartbeat
+	frameGlobalRegister
+	frameGlobalUnregister
+	frameGlobalSync
+	frameSpawn
+	frameWatch
+	frameExited
+	frameExitSignal
+)
+
+//
+// maxFrameSize limits the size of incoming frames
+//
+const maxFrameSize = 64 << 20
+
+//
+// frame is the unit of the node protocol. Frames are written as 4 bytes of
+// big endian length followed by the frame encoded by the codec of the node.
+// Hello frames are encoded with gob and carry the name of the codec
+//
+type frame struct {
+	Type     frameType
+	Ref      uint64 // request id, replies have the id of the request
+	To       uint64 // target pid id
+	Node     string // node name in hello
+	Codec    string // codec name in hello
+	Prefix   string
+	Name     Term
+	Data     Term
+	Err      *wireError
+	Prio     Priority
+	Deadline time.Time
+}
+
+//
+// wireError transfers errors and exit reasons. Known errors are restored
+// on the other side, others are restored with the same message
+//
+type wireError struct {
+	Kind  string
+	Msg   string
+	Panic string
+}
+
+var wireErrors = []struct {
+	kind string
+	err  error
+}{
+	{"noproc", GsNoProcError},
+	{"timeout", GsTimeoutError},
+	{"normal", Normal},
+	{"shutdown", Shutdown},
+	{"killed", Killed},
+	{"badreply", ErrBadReply},
+	{"mailboxfull", ErrMailboxFull},
+	{"dropped", errDropped},
+	{"mailboxwait", errMailboxWait},
+	{"envshutdown", ErrEnvShutdown},
+}
+
+//
+// wirePid is the encoded pid: the node of the process and its id
+//
+type wirePid struct {
+	Node string
+	Id   uint64
+}
+
+//
+// remoteError is the error restored from the other node, errors.Is and
+// errors.As match the known error
+//
+type remoteError struct {
+	msg string
+	err error
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
+
+// ---------------------------------------------------------------------------
+func encodeError(err error) *wireError {
+	if err == nil {
+		return nil
+	}
+
+	var crash *Crash
+	if errors.As(err, &crash) {
+		return &wireError{
+			Kind:  "crash",
+			Msg:   err.Error(),
+			Panic: fmt.Sprintf("%v", crash.Panic),
+		}
+	}
+
+	for _, known := range wireErrors {
+		if errors.Is(err, known.err) {
+			return &wireError{Kind: known.kind, Msg: err.Error()}
+		}
+	}
+
+	return &wireError{Msg: err.Error()}
+}
+
+func decodeError(w *wireError) error {
+	if w == nil {
+		return nil
+	}
+
+	if w.Kind == "crash" {
+		return &remoteError{w.Msg, &Crash{Panic: w.Panic}}
+	}
+
+	for _, known := range wireErrors {
+		if w.Kind != known.kind {
+			continue
+		}
+		if w.Msg == known.err.Error() {
+			return known.err
+		}
+		return &remoteError{w.Msg, known.err}
+	}
+
+	return errors.New(w.Msg)
+}
+
+// ---------------------------------------------------------------------------
+func writeFrame(w io.Writer, c Codec, f *frame) error {
+	b, err := encodeFrame(c, f)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+}
+
+//
+// encodeFrame returns the frame with the length header
+//
+func encodeFrame(c Codec, f *frame) ([]byte, error) {
+	data, err := c.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes is too large", len(data))
+	}
+
+	b := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(b, uint32(len(data)))
+	copy(b[4:], data)
+
+	return b, nil
+}
+
+func readFrame(r io.Reader, c Codec) (*frame, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes is too large", size)
+	}
+
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	t, err := c.Unmarshal(b)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := t.(*frame)
+	if !ok || f == nil {
+		return nil, fmt.Errorf("bad frame %T", t)
+	}
+
+	return f, nil
+}
+
+// ---------------------------------------------------------------------------
+//
+// GobEncode encodes the pid as node name and id
+//
+func (pid *Pid) GobEncode() ([]byte, error) {
+	return gobEncode(wirePid{pid.Node(), pid.Id()})
+}
+
+//
+// GobDecode decodes the pid. The decoded pid is replaced with the pid of
+// the process by the node transport
+//
+func (pid *Pid) GobDecode(b []byte) error {
+	var w wirePid
+	if err := gobDecode(b, &w); err != nil {
+		return err
+	}
+
+	pid.id = w.Id
+	pid.wireNode = w.Node
+
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+var pidType = reflect.TypeOf((*Pid)(nil))
+
+//
+// resolvePids replaces decoded pids in the term with pids of processes:
+// local pids for the local node and remote pids for connected nodes
+//
+func (a *Act) resolvePids(t Term) Term {
+	if t == nil {
+		return nil
+	}
+
+	return a.resolveValue(reflect.ValueOf(t)).Interface()
+}
+
+func (a *Act) resolveValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		if v.Type() == pidType {
+			return reflect.ValueOf(a.resolvePid(v.Interface().(*Pid)))
+		}
+		a.resolveInPlace(v.Elem())
+
+	case reflect.Interface:
+		if !v.IsNil() {
+			return a.resolveValue(v.Elem())
+		}
+
+	case reflect.Struct, reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		a.resolveInPlace(c)
+		return c
+
+	case reflect.Slice:
+		if !v.IsNil() {
+			a.resolveInPlace(v)
+		}
+
+	case reflect.Map:
+		if isScalar(v.Type().Elem()) {
+			return v
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			v.SetMapIndex(iter.Key(), a.resolveValue(iter.Value()))
+		}
+	}
+
+	return v
+}
+
+func (a *Act) resolveInPlace(v reflect.Value) {
+	switch v.Kind() {
+
+	case reflect.Ptr, reflect.Interface, reflect.Map:
+		if r := a.resolveValue(v); v.CanSet() && r.IsValid() {
+			v.Set(r)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				a.resolveInPlace(f)
+			}
+		}
+
+	case reflect.Array, reflect.Slice:
+		if isScalar(v.Type().Elem()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			a.resolveInPlace(v.Index(i))
+		}
+	}
+}
+
+func isScalar(t reflect.Type) bool {
+	k := t.Kind()
+
+	return k <= reflect.Complex128 || k == reflect.String
+}